@@ -0,0 +1,108 @@
+package ringbuffer
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+)
+
+// Frame layout
+//
+// sync:       4 bytes (frameSync)
+// length:     4 bytes, little endian length of payload
+// header crc: 4 bytes, little endian CRC32 (IEEE) of sync and length
+// payload:    length bytes
+// crc:        4 bytes, little endian CRC32 (IEEE) of length and payload
+//
+// The sync word lets the decoder find the start of the next frame after
+// corruption, and the CRC lets it reject frames that happen to contain
+// a sync word, or that were damaged in transit. The header CRC lets the
+// decoder reject a damaged length immediately, instead of waiting for
+// up to MaxPayload bytes to arrive before the trailing CRC can be checked.
+
+// DefaultMaxFramePayload is the largest frame payload accepted by a FrameDecoder
+// whose MaxPayload is zero.
+const DefaultMaxFramePayload = 1024 * 1024
+
+const frameHeaderSize = 12 // sync + length + header crc
+const frameTrailerSize = 4 // crc
+
+var frameSync = [4]byte{0xa5, 0x5a, 0xc3, 0x3c}
+
+// WriteFrame writes payload into the ring, wrapped in a frame that can be read with FrameDecoder
+func WriteFrame(r *Ring, payload []byte) {
+	var header [frameHeaderSize]byte
+	copy(header[:], frameSync[:])
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(header[8:], crc32.ChecksumIEEE(header[:8]))
+	crc := crc32.ChecksumIEEE(header[4:8])
+	crc = crc32.Update(crc, crc32.IEEETable, payload)
+	var trailer [frameTrailerSize]byte
+	binary.LittleEndian.PutUint32(trailer[:], crc)
+	r.Write(header[:])
+	r.Write(payload)
+	r.Write(trailer[:])
+}
+
+// FrameDecoder reads frames written by WriteFrame out of a ring.
+// When the decoder encounters corrupt data, it discards bytes until it
+// finds the next valid frame.
+// The zero value is ready to use.
+type FrameDecoder struct {
+	MaxPayload int // Frames with a longer payload are treated as corrupt. If zero, DefaultMaxFramePayload is used.
+	Discarded  int // Total number of bytes discarded while searching for a valid frame
+}
+
+// Next returns the payload of the next valid frame in the ring, and consumes the frame.
+// If the ring does not yet contain a complete frame, then Next returns false, and the
+// partial frame is left in the ring, so that you can call Next again once more data has arrived.
+func (d *FrameDecoder) Next(r *Ring) (payload []byte, ok bool) {
+	maxPayload := d.MaxPayload
+	if maxPayload == 0 {
+		maxPayload = DefaultMaxFramePayload
+	}
+	var header [frameHeaderSize]byte
+	for {
+		n := r.peek(header[:], 0)
+		if n < len(frameSync) {
+			return nil, false
+		}
+		if [4]byte(header[:4]) != frameSync {
+			d.skip(r)
+			continue
+		}
+		if n < frameHeaderSize {
+			return nil, false
+		}
+		if crc32.ChecksumIEEE(header[:8]) != binary.LittleEndian.Uint32(header[8:]) {
+			d.skip(r)
+			continue
+		}
+		length := binary.LittleEndian.Uint32(header[4:])
+		if uint64(length) > uint64(maxPayload) {
+			d.skip(r)
+			continue
+		}
+		total := frameHeaderSize + int(length) + frameTrailerSize
+		if r.Len() < total {
+			return nil, false
+		}
+		payload = make([]byte, length)
+		r.peek(payload, frameHeaderSize)
+		var trailer [frameTrailerSize]byte
+		r.peek(trailer[:], frameHeaderSize+int(length))
+		crc := crc32.ChecksumIEEE(header[4:8])
+		crc = crc32.Update(crc, crc32.IEEETable, payload)
+		if crc != binary.LittleEndian.Uint32(trailer[:]) {
+			d.skip(r)
+			continue
+		}
+		r.discard(total)
+		return payload, true
+	}
+}
+
+// Discard one byte, so that we can search for the next sync word
+func (d *FrameDecoder) skip(r *Ring) {
+	r.discard(1)
+	d.Discarded++
+}
@@ -0,0 +1,77 @@
+package ringbuffer
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrame(t *testing.T) {
+	truth := makeTruth()
+	r := &Ring{}
+	d := FrameDecoder{}
+
+	// empty and partial frames
+	_, ok := d.Next(r)
+	require.False(t, ok)
+	WriteFrame(r, truth[:100])
+	partial := r.Split(50)
+	_, ok = d.Next(partial)
+	require.False(t, ok)
+	partial.Append(r)
+	r = partial
+	payload, ok := d.Next(r)
+	require.True(t, ok)
+	require.Equal(t, truth[:100], payload)
+	require.Equal(t, 0, r.Len())
+
+	// zero length payload
+	WriteFrame(r, nil)
+	payload, ok = d.Next(r)
+	require.True(t, ok)
+	require.Equal(t, 0, len(payload))
+
+	// garbage before a frame, and a corrupt frame, which wraps around the buffer
+	r.Write([]byte{1, 2, 3, frameSync[0], frameSync[1]})
+	WriteFrame(r, truth[:200])
+	corrupt := r.data[(r.head-10)&r.mask()]
+	r.data[(r.head-10)&r.mask()] = corrupt + 1
+	WriteFrame(r, truth[200:300])
+	payload, ok = d.Next(r)
+	require.True(t, ok)
+	require.Equal(t, truth[200:300], payload)
+	require.Equal(t, 5+frameHeaderSize+200+frameTrailerSize, d.Discarded)
+	require.Equal(t, 0, r.Len())
+
+	// payload too large
+	d = FrameDecoder{MaxPayload: 10}
+	WriteFrame(r, truth[:11])
+	WriteFrame(r, truth[:10])
+	payload, ok = d.Next(r)
+	require.True(t, ok)
+	require.Equal(t, truth[:10], payload)
+}
+
+func TestFrameCorruptHeader(t *testing.T) {
+	truth := makeTruth()
+	r := &Ring{}
+	d := FrameDecoder{}
+
+	// A header with a valid sync word, but a damaged length, must be skipped
+	// immediately, instead of waiting for 100000 bytes to arrive.
+	var header [frameHeaderSize]byte
+	copy(header[:], frameSync[:])
+	binary.LittleEndian.PutUint32(header[4:], 100000)
+	r.Write(header[:])
+	for i := 0; i < 50; i++ {
+		WriteFrame(r, truth[i:i+1])
+	}
+	for i := 0; i < 50; i++ {
+		payload, ok := d.Next(r)
+		require.True(t, ok)
+		require.Equal(t, truth[i:i+1], payload)
+	}
+	require.Equal(t, frameHeaderSize, d.Discarded)
+	require.Equal(t, 0, r.Len())
+}
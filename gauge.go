@@ -0,0 +1,24 @@
+package ringbuffer
+
+import "sync/atomic"
+
+// Gauge publishes the length and weight of a ring, so that they can be observed
+// by other goroutines (eg a metrics exporter), while the ring itself is owned
+// by a single goroutine. The ring's owner attaches a Gauge with SetGauge, and
+// thereafter every change to the ring is published with an atomic store.
+// Gauge is supported by Ring and WeightedRingT.
+type Gauge struct {
+	length atomic.Int64
+	weight atomic.Int64
+}
+
+// Len returns the most recently published length of the ring
+func (g *Gauge) Len() int {
+	return int(g.length.Load())
+}
+
+// Weight returns the most recently published weight of the ring.
+// For a Ring, this is always zero.
+func (g *Gauge) Weight() int64 {
+	return g.weight.Load()
+}
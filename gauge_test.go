@@ -0,0 +1,56 @@
+package ringbuffer
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGauge(t *testing.T) {
+	g := &Gauge{}
+	r := &Ring{}
+	r.SetGauge(g)
+	r.Write(makeTruth()[:100])
+	require.Equal(t, 100, g.Len())
+	r.DirectRead(30)
+	require.Equal(t, 70, g.Len())
+	s := r.Split(1000)
+	require.Equal(t, 0, g.Len())
+	r.Append(s)
+	require.Equal(t, 70, g.Len())
+	r.Reset()
+	require.Equal(t, 0, g.Len())
+
+	wg := &Gauge{}
+	w := NewWeightedRingT[thing](10)
+	w.SetGauge(wg)
+	w.Add(3, &thing{})
+	w.Add(4, &thing{})
+	require.Equal(t, 2, wg.Len())
+	require.Equal(t, int64(7), wg.Weight())
+	w.Next()
+	require.Equal(t, 1, wg.Len())
+	require.Equal(t, int64(4), wg.Weight())
+}
+
+// Run with -race to verify that observing a gauge is safe
+func TestGaugeConcurrent(t *testing.T) {
+	g := &Gauge{}
+	w := NewWeightedRingT[thing](100)
+	w.SetGauge(g)
+	var wait sync.WaitGroup
+	wait.Add(1)
+	go func() {
+		defer wait.Done()
+		for i := 0; i < 1000; i++ {
+			if g.Weight() > 100 {
+				t.Errorf("Gauge weight %v exceeds MaxWeight", g.Weight())
+			}
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		w.Add(int64(i%10), &thing{})
+	}
+	wait.Wait()
+}
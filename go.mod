@@ -1,10 +1,11 @@
 module github.com/bmharper/ringbuffer
 
-go 1.18
+go 1.23
+
+require github.com/stretchr/testify v1.7.2
 
 require (
 	github.com/davecgh/go-spew v1.1.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stretchr/testify v1.7.2 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,102 @@
+package ringbuffer
+
+import (
+	"slices"
+	"sort"
+)
+
+// Histogram counts the last N samples into buckets, so that quantiles over the
+// window can be estimated without sorting the samples.
+// The samples are kept in a ring, and the bucket counts are updated as samples
+// enter and leave the window, so Add is O(log B), and Quantile is O(B), where B
+// is the number of buckets.
+type Histogram struct {
+	samples RingP[float64]
+	window  int       // Number of samples in the window
+	bounds  []float64 // Upper bound of each bucket, sorted ascending
+	counts  []int     // len(counts) = len(bounds) + 1. The last bucket holds samples above the highest bound.
+}
+
+// HistogramSnapshot is a copy of the bucket counts of a Histogram.
+// Counts[i] is the number of samples v where Bounds[i-1] < v <= Bounds[i].
+// Counts[len(Bounds)] is the number of samples that are greater than the highest bound.
+type HistogramSnapshot struct {
+	Bounds []float64
+	Counts []int
+	Total  int // Sum of Counts
+}
+
+// NewHistogram creates a Histogram over the last window samples, with the given
+// bucket upper bounds. bounds must be sorted in ascending order, and must not be empty.
+func NewHistogram(window int, bounds []float64) *Histogram {
+	if window < 1 {
+		panic("window must be at least 1")
+	}
+	if len(bounds) == 0 || !slices.IsSorted(bounds) {
+		panic("bounds must be sorted, and not empty")
+	}
+	return &Histogram{
+		samples: NewRingPCap[float64](window),
+		window:  window,
+		bounds:  slices.Clone(bounds),
+		counts:  make([]int, len(bounds)+1),
+	}
+}
+
+// Add adds a sample to the window. If the window is full, the oldest sample is removed.
+func (h *Histogram) Add(v float64) {
+	if h.samples.Len() == h.window {
+		h.counts[h.bucket(h.samples.Next())]--
+	}
+	h.samples.Add(v)
+	h.counts[h.bucket(v)]++
+}
+
+// Len returns the number of samples in the window
+func (h *Histogram) Len() int {
+	return h.samples.Len()
+}
+
+// Quantile returns an estimate of the q-quantile of the samples in the window,
+// for example Quantile(0.99) is the 99th percentile. q is clamped to [0, 1].
+// The estimate is interpolated linearly inside the bucket that holds the quantile.
+// If the quantile falls in the lowest bucket, the lowest bound is returned,
+// and if it falls above the highest bound, the highest bound is returned.
+// Returns zero if the window is empty.
+func (h *Histogram) Quantile(q float64) float64 {
+	n := h.samples.Len()
+	if n == 0 {
+		return 0
+	}
+	q = min(max(q, 0), 1)
+	rank := q * float64(n)
+	cum := 0
+	for i, c := range h.counts {
+		if c == 0 || float64(cum+c) < rank {
+			cum += c
+			continue
+		}
+		if i == 0 {
+			return h.bounds[0]
+		} else if i == len(h.bounds) {
+			return h.bounds[i-1]
+		}
+		lower, upper := h.bounds[i-1], h.bounds[i]
+		return lower + (upper-lower)*(rank-float64(cum))/float64(c)
+	}
+	return h.bounds[len(h.bounds)-1]
+}
+
+// Snapshot returns a copy of the current bucket counts
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	return HistogramSnapshot{
+		Bounds: slices.Clone(h.bounds),
+		Counts: slices.Clone(h.counts),
+		Total:  h.samples.Len(),
+	}
+}
+
+// Returns the index of the bucket that v belongs in
+func (h *Histogram) bucket(v float64) int {
+	return sort.SearchFloat64s(h.bounds, v)
+}
@@ -0,0 +1,37 @@
+package ringbuffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram(4, []float64{10, 20, 30})
+	require.Equal(t, 0.0, h.Quantile(0.5))
+
+	for _, v := range []float64{5, 15, 15, 25} {
+		h.Add(v)
+	}
+	require.Equal(t, HistogramSnapshot{
+		Bounds: []float64{10, 20, 30},
+		Counts: []int{1, 2, 1, 0},
+		Total:  4,
+	}, h.Snapshot())
+	require.Equal(t, 10.0, h.Quantile(0))
+	require.Equal(t, 10.0, h.Quantile(0.25))
+	require.Equal(t, 15.0, h.Quantile(0.5))
+	require.Equal(t, 20.0, h.Quantile(0.75))
+	require.Equal(t, 30.0, h.Quantile(1))
+
+	// 5 and 15 leave the window, so bucket counts must follow
+	h.Add(35)
+	h.Add(10)
+	require.Equal(t, []int{1, 1, 1, 1}, h.Snapshot().Counts)
+	require.Equal(t, 4, h.Len())
+	require.Equal(t, 30.0, h.Quantile(1))
+
+	require.Panics(t, func() { NewHistogram(0, []float64{1}) })
+	require.Panics(t, func() { NewHistogram(1, []float64{2, 1}) })
+	require.Panics(t, func() { NewHistogram(1, nil) })
+}
@@ -0,0 +1,89 @@
+package ringbuffer
+
+// MovingAverage computes a simple moving average over the last N samples,
+// and an exponentially weighted moving average over all samples.
+// Both averages are maintained incrementally, so Add, SMA and EWMA are all O(1).
+// To stop floating point error from accumulating in the running sum of the simple
+// moving average, the sum is recomputed from the samples once every window samples,
+// so Add is amortized O(1).
+type MovingAverage struct {
+	samples RingP[float64]
+	window  int     // Number of samples in the simple moving average
+	sum     float64 // Sum of the samples in the ring
+	stale   int     // Number of incremental updates to sum since it was last recomputed
+	alpha   float64 // Weight of each new sample in the EWMA
+	ewma    float64
+	count   uint64 // Total number of samples ever added
+}
+
+// NewMovingAverage creates a MovingAverage that computes the simple moving average
+// over the last window samples, and an exponentially weighted moving average where
+// each new sample has a weight of alpha. window must be at least 1, and alpha must
+// be in the range (0, 1].
+func NewMovingAverage(window int, alpha float64) *MovingAverage {
+	if window < 1 {
+		panic("window must be at least 1")
+	}
+	if alpha <= 0 || alpha > 1 {
+		panic("alpha must be in the range (0, 1]")
+	}
+	return &MovingAverage{
+		samples: NewRingPCap[float64](window),
+		window:  window,
+		alpha:   alpha,
+	}
+}
+
+// Add adds a sample to both averages.
+func (m *MovingAverage) Add(v float64) {
+	if m.samples.Len() == m.window {
+		m.sum -= m.samples.Next()
+	}
+	m.samples.Add(v)
+	m.sum += v
+	m.stale++
+	if m.stale >= m.window {
+		m.sum = SumOf(m.samples.All())
+		m.stale = 0
+	}
+	if m.count == 0 {
+		m.ewma = v
+	} else {
+		m.ewma += m.alpha * (v - m.ewma)
+	}
+	m.count++
+}
+
+// Len returns the number of samples in the simple moving average window
+func (m *MovingAverage) Len() int {
+	return m.samples.Len()
+}
+
+// Count returns the total number of samples that have been added
+func (m *MovingAverage) Count() uint64 {
+	return m.count
+}
+
+// SMA returns the mean of the last window samples, or zero if no samples have been added.
+// If fewer than window samples have been added, then SMA is the mean of those samples.
+func (m *MovingAverage) SMA() float64 {
+	if m.samples.Len() == 0 {
+		return 0
+	}
+	return m.sum / float64(m.samples.Len())
+}
+
+// EWMA returns the exponentially weighted moving average, or zero if no samples have been added.
+// The first sample initializes the average.
+func (m *MovingAverage) EWMA() float64 {
+	return m.ewma
+}
+
+// Reset removes all samples, and resets both averages to zero.
+func (m *MovingAverage) Reset() {
+	m.samples.Clear()
+	m.sum = 0
+	m.stale = 0
+	m.ewma = 0
+	m.count = 0
+}
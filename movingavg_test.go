@@ -0,0 +1,53 @@
+package ringbuffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMovingAverage(t *testing.T) {
+	m := NewMovingAverage(3, 0.5)
+	require.Equal(t, 0.0, m.SMA())
+	require.Equal(t, 0.0, m.EWMA())
+
+	m.Add(4)
+	require.Equal(t, 4.0, m.SMA())
+	require.Equal(t, 4.0, m.EWMA())
+	m.Add(8)
+	require.Equal(t, 6.0, m.SMA())
+	require.Equal(t, 6.0, m.EWMA())
+	m.Add(0)
+	require.Equal(t, 4.0, m.SMA())
+	require.Equal(t, 3.0, m.EWMA())
+	// The window is 3, even though the underlying ring can hold more
+	m.Add(10)
+	require.Equal(t, 3, m.Len())
+	require.Equal(t, uint64(4), m.Count())
+	require.Equal(t, 6.0, m.SMA())
+	require.Equal(t, 6.5, m.EWMA())
+
+	m.Reset()
+	require.Equal(t, 0, m.Len())
+	require.Equal(t, 0.0, m.SMA())
+	m.Add(1)
+	require.Equal(t, 1.0, m.EWMA())
+
+	require.Panics(t, func() { NewMovingAverage(0, 0.5) })
+	require.Panics(t, func() { NewMovingAverage(1, 0) })
+}
+
+func TestMovingAverageDrift(t *testing.T) {
+	// Once 1e17 leaves the window, a purely incremental sum would have lost the 1s
+	// that were added while it was present.
+	m := NewMovingAverage(4, 0.5)
+	m.Add(1e17)
+	for i := 0; i < 8; i++ {
+		m.Add(1)
+	}
+	require.Equal(t, 1.0, m.SMA())
+	for i := 0; i < 1000; i++ {
+		m.Add(0.1)
+	}
+	require.InDelta(t, 0.1, m.SMA(), 1e-15)
+}
@@ -0,0 +1,242 @@
+package ringbuffer
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// MPMCP is a bounded queue that holds values of a generic type T.
+// It is safe for any number of producer and consumer goroutines to use the queue concurrently.
+// This is Dmitry Vyukov's bounded MPMC queue, where every slot carries a sequence number
+// that tells producers and consumers whether the slot is ready for them.
+// See https://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+//
+// Progress guarantees: TryPush and TryPop never block, and never take a lock.
+// They retry only when a compare-and-swap fails, which means that another goroutine
+// succeeded, so under contention an individual call may retry many times (the queue
+// is not wait-free). One caveat: a producer that is descheduled after claiming a slot,
+// but before storing its item, holds up consumers at that slot until it runs again.
+// During that window TryPop reports the queue as empty, even if later slots are full.
+// Items are popped in the order in which producers claimed their slots.
+//
+// When popping an item from the queue, we set it's slot to the zero value,
+// to ensure that the garbage collector can reclaim anything that the item references.
+type MPMCP[T any] struct {
+	slots []mpmcSlot[T] // len(slots) is a power of 2.
+	mask  uint64        // mask = len(slots) - 1
+	_     [cacheLineSize]byte
+	head  atomic.Uint64 // write into head
+	_     [cacheLineSize - 8]byte
+	tail  atomic.Uint64 // read from tail
+	_     [cacheLineSize - 8]byte
+
+	// Goroutines blocked in Push or Pop park on these channels, instead of spinning.
+	// Each channel holds at most one wakeup. A waiter that wakes up and succeeds
+	// passes the wakeup on, so that a wakeup that was dropped because the channel
+	// was full is never lost.
+	pushWaiters atomic.Int32
+	popWaiters  atomic.Int32
+	notFull     chan struct{}
+	notEmpty    chan struct{}
+}
+
+type mpmcSlot[T any] struct {
+	seq  atomic.Uint64
+	item T
+}
+
+// NewMPMCP creates a new queue that can hold capacity items.
+// capacity must be a power of 2, and at least 2.
+func NewMPMCP[T any](capacity int) *MPMCP[T] {
+	if (capacity&(capacity-1)) != 0 || capacity < 2 {
+		panic("capacity must be a power of 2, and minimum 2")
+	}
+	q := &MPMCP[T]{
+		slots:    make([]mpmcSlot[T], capacity),
+		mask:     uint64(capacity) - 1,
+		notFull:  make(chan struct{}, 1),
+		notEmpty: make(chan struct{}, 1),
+	}
+	for i := range q.slots {
+		q.slots[i].seq.Store(uint64(i))
+	}
+	return q
+}
+
+// Capacity is the maximum number of items in the queue
+func (q *MPMCP[T]) Capacity() int {
+	return len(q.slots)
+}
+
+// TryPush adds an item to the queue, and returns true.
+// If the queue is full, then TryPush returns false.
+func (q *MPMCP[T]) TryPush(item T) bool {
+	pos := q.head.Load()
+	for {
+		slot := &q.slots[pos&q.mask]
+		dif := int64(slot.seq.Load() - pos)
+		if dif == 0 {
+			// The slot is free. Try to claim it.
+			if q.head.CompareAndSwap(pos, pos+1) {
+				slot.item = item
+				slot.seq.Store(pos + 1)
+				wake(&q.popWaiters, q.notEmpty)
+				return true
+			}
+			pos = q.head.Load()
+		} else if dif < 0 {
+			// The slot still holds an item from the previous lap, so the queue is full
+			return false
+		} else {
+			// Another producer claimed this slot
+			pos = q.head.Load()
+		}
+	}
+}
+
+// TryPop removes and returns the next item in the queue, and true.
+// If the queue is empty, TryPop returns the zero value and false.
+func (q *MPMCP[T]) TryPop() (T, bool) {
+	var zero T
+	pos := q.tail.Load()
+	for {
+		slot := &q.slots[pos&q.mask]
+		dif := int64(slot.seq.Load() - (pos + 1))
+		if dif == 0 {
+			// The slot holds an item. Try to claim it.
+			if q.tail.CompareAndSwap(pos, pos+1) {
+				item := slot.item
+				slot.item = zero // erase item, so that the garbage collector can do it's job
+				slot.seq.Store(pos + q.mask + 1)
+				wake(&q.pushWaiters, q.notFull)
+				return item, true
+			}
+			pos = q.tail.Load()
+		} else if dif < 0 {
+			// The slot has not been written yet, so the queue is empty
+			return zero, false
+		} else {
+			// Another consumer claimed this slot
+			pos = q.tail.Load()
+		}
+	}
+}
+
+// Push adds an item to the queue, waiting for space if the queue is full.
+// While waiting, the goroutine is parked, so it does not consume CPU.
+// Returns ctx.Err() if ctx is done before the item could be added.
+func (q *MPMCP[T]) Push(ctx context.Context, item T) error {
+	woken := false
+	for {
+		if q.TryPush(item) {
+			if woken {
+				wake(&q.pushWaiters, q.notFull)
+			}
+			return nil
+		}
+		// Register as a waiter before checking again, so that a concurrent
+		// TryPop either sees us, or we see the space that it freed.
+		q.pushWaiters.Add(1)
+		if q.TryPush(item) {
+			q.pushWaiters.Add(-1)
+			return nil
+		}
+		select {
+		case <-q.notFull:
+			q.pushWaiters.Add(-1)
+			woken = true
+		case <-ctx.Done():
+			q.pushWaiters.Add(-1)
+			return ctx.Err()
+		}
+	}
+}
+
+// Pop removes and returns the next item in the queue, waiting for an item if the queue is empty.
+// While waiting, the goroutine is parked, so it does not consume CPU.
+// Returns ctx.Err() if ctx is done before an item is available.
+func (q *MPMCP[T]) Pop(ctx context.Context) (T, error) {
+	woken := false
+	for {
+		if item, ok := q.TryPop(); ok {
+			if woken {
+				wake(&q.popWaiters, q.notEmpty)
+			}
+			return item, nil
+		}
+		// Register as a waiter before checking again, so that a concurrent
+		// TryPush either sees us, or we see the item that it added.
+		q.popWaiters.Add(1)
+		if item, ok := q.TryPop(); ok {
+			q.popWaiters.Add(-1)
+			return item, nil
+		}
+		select {
+		case <-q.notEmpty:
+			q.popWaiters.Add(-1)
+			woken = true
+		case <-ctx.Done():
+			q.popWaiters.Add(-1)
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// If there are any waiters, wake one of them up
+func wake(waiters *atomic.Int32, ch chan struct{}) {
+	if waiters.Load() > 0 {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// MPMC is a bounded queue that holds pointers to a generic type T.
+// It is safe for any number of producer and consumer goroutines to use the queue concurrently.
+// MPMC has the same algorithm and progress guarantees as MPMCP.
+// When popping an item from the queue, we set it's pointer to nil,
+// to ensure that the garbage collector can reclaim the memory for that item.
+type MPMC[T any] struct {
+	queue *MPMCP[*T]
+}
+
+// NewMPMC creates a new queue that can hold capacity items.
+// capacity must be a power of 2, and at least 2.
+func NewMPMC[T any](capacity int) *MPMC[T] {
+	return &MPMC[T]{
+		queue: NewMPMCP[*T](capacity),
+	}
+}
+
+// Capacity is the maximum number of items in the queue
+func (q *MPMC[T]) Capacity() int {
+	return q.queue.Capacity()
+}
+
+// TryPush adds an item to the queue, and returns true.
+// If the queue is full, then TryPush returns false.
+func (q *MPMC[T]) TryPush(item *T) bool {
+	return q.queue.TryPush(item)
+}
+
+// TryPop removes and returns the next item in the queue, or nil if the queue is empty
+func (q *MPMC[T]) TryPop() *T {
+	item, _ := q.queue.TryPop()
+	return item
+}
+
+// Push adds an item to the queue, waiting for space if the queue is full.
+// While waiting, the goroutine is parked, so it does not consume CPU.
+// Returns ctx.Err() if ctx is done before the item could be added.
+func (q *MPMC[T]) Push(ctx context.Context, item *T) error {
+	return q.queue.Push(ctx, item)
+}
+
+// Pop removes and returns the next item in the queue, waiting for an item if the queue is empty.
+// While waiting, the goroutine is parked, so it does not consume CPU.
+// Returns ctx.Err() if ctx is done before an item is available.
+func (q *MPMC[T]) Pop(ctx context.Context) (*T, error) {
+	return q.queue.Pop(ctx)
+}
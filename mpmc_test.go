@@ -0,0 +1,150 @@
+package ringbuffer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMPMC(t *testing.T) {
+	q := NewMPMC[obj](4)
+	require.Nil(t, q.TryPop())
+	for i := 0; i < 4; i++ {
+		require.True(t, q.TryPush(&obj{id: i}))
+	}
+	require.False(t, q.TryPush(&obj{id: 4}))
+	for i := 0; i < 4; i++ {
+		require.Equal(t, i, q.TryPop().id)
+	}
+	require.Nil(t, q.TryPop())
+	require.Panics(t, func() { NewMPMC[obj](1) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	item, err := q.Pop(ctx)
+	require.Nil(t, item)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// Run with -race to verify the memory ordering
+func TestMPMCConcurrent(t *testing.T) {
+	q := NewMPMC[obj](16)
+	const producers = 4
+	const consumers = 4
+	const n = 10000
+	ctx := context.Background()
+	var wait sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wait.Add(1)
+		go func(p int) {
+			defer wait.Done()
+			for i := 0; i < n; i++ {
+				q.Push(ctx, &obj{id: p*n + i})
+			}
+		}(p)
+	}
+	var lock sync.Mutex
+	seen := make([]bool, producers*n)
+	for c := 0; c < consumers; c++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for i := 0; i < producers*n/consumers; i++ {
+				item, _ := q.Pop(ctx)
+				lock.Lock()
+				seen[item.id] = true
+				lock.Unlock()
+			}
+		}()
+	}
+	wait.Wait()
+	for _, s := range seen {
+		require.True(t, s)
+	}
+}
+
+func TestMPMCP(t *testing.T) {
+	q := NewMPMCP[pod](4)
+	_, ok := q.TryPop()
+	require.False(t, ok)
+	for i := 0; i < 4; i++ {
+		require.True(t, q.TryPush(pod{id: i}))
+	}
+	require.False(t, q.TryPush(pod{id: 4}))
+	for i := 0; i < 4; i++ {
+		item, ok := q.TryPop()
+		require.True(t, ok)
+		require.Equal(t, i, item.id)
+	}
+	_, ok = q.TryPop()
+	require.False(t, ok)
+	require.Panics(t, func() { NewMPMCP[pod](1) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := q.Pop(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// Goroutines that are parked in Pop and Push must all be woken up,
+// even when several items arrive at once
+func TestMPMCPParked(t *testing.T) {
+	q := NewMPMCP[int](2)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	const waiters = 8
+	results := make(chan int, waiters)
+	for i := 0; i < waiters; i++ {
+		go func() {
+			item, err := q.Pop(ctx)
+			if err != nil {
+				t.Error(err)
+			}
+			results <- item
+		}()
+	}
+	// Give the consumers time to park
+	time.Sleep(10 * time.Millisecond)
+	var wait sync.WaitGroup
+	for i := 0; i < waiters; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			if err := q.Push(ctx, i); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wait.Wait()
+	seen := make([]bool, waiters)
+	for i := 0; i < waiters; i++ {
+		seen[<-results] = true
+	}
+	for _, s := range seen {
+		require.True(t, s)
+	}
+
+	// Parked producers are woken by consumers
+	q.TryPush(0)
+	q.TryPush(1)
+	for i := 2; i < 2+waiters; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			if err := q.Push(ctx, i); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	for i := 0; i < 2+waiters; i++ {
+		_, err := q.Pop(ctx)
+		require.NoError(t, err)
+	}
+	wait.Wait()
+	_, ok := q.TryPop()
+	require.False(t, ok)
+}
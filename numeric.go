@@ -0,0 +1,60 @@
+package ringbuffer
+
+import "iter"
+
+// Number is the set of types that the numeric aggregate functions accept.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// The aggregate functions below accept any sequence of values, such as the
+// All() iterator of a RingP or RingV. For example: SumOf(ring.All())
+
+// SumOf returns the sum of all the values in seq, or zero if seq is empty.
+// The sum is computed in T, so it can overflow for small integer types.
+func SumOf[T Number](seq iter.Seq[T]) T {
+	var sum T
+	for v := range seq {
+		sum += v
+	}
+	return sum
+}
+
+// MeanOf returns the arithmetic mean of all the values in seq, or zero if seq is empty.
+// The sum is computed in float64, so it does not overflow for integer types.
+func MeanOf[T Number](seq iter.Seq[T]) float64 {
+	sum := 0.0
+	n := 0
+	for v := range seq {
+		sum += float64(v)
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// MinOf returns the smallest value in seq, and true.
+// If seq is empty, MinOf returns zero and false.
+func MinOf[T Number](seq iter.Seq[T]) (m T, ok bool) {
+	for v := range seq {
+		if !ok || v < m {
+			m, ok = v, true
+		}
+	}
+	return
+}
+
+// MaxOf returns the largest value in seq, and true.
+// If seq is empty, MaxOf returns zero and false.
+func MaxOf[T Number](seq iter.Seq[T]) (m T, ok bool) {
+	for v := range seq {
+		if !ok || v > m {
+			m, ok = v, true
+		}
+	}
+	return
+}
@@ -0,0 +1,50 @@
+package ringbuffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNumeric(t *testing.T) {
+	ring := NewRingP[int](4)
+	require.Equal(t, 0, SumOf(ring.All()))
+	require.Equal(t, 0.0, MeanOf(ring.All()))
+	_, ok := MinOf(ring.All())
+	require.False(t, ok)
+	_, ok = MaxOf(ring.All())
+	require.False(t, ok)
+
+	// 5, 3, 9 remain after 1 is erased
+	for _, v := range []int{1, 5, 3, 9} {
+		ring.Add(v)
+	}
+	require.Equal(t, 17, SumOf(ring.All()))
+	require.InDelta(t, 17.0/3.0, MeanOf(ring.All()), 1e-9)
+	m, ok := MinOf(ring.All())
+	require.True(t, ok)
+	require.Equal(t, 3, m)
+	m, ok = MaxOf(ring.All())
+	require.True(t, ok)
+	require.Equal(t, 9, m)
+
+	f := NewRingP[float32](4)
+	f.Add(-1.5)
+	f.Add(2.5)
+	require.Equal(t, float32(1), SumOf(f.All()))
+	require.Equal(t, 0.5, MeanOf(f.All()))
+
+	// RingV, with a negative minimum
+	v := NewRingV[int64](3)
+	for _, x := range []int64{-7, 4, -2, 6} {
+		v.Add(x)
+	}
+	require.Equal(t, int64(8), SumOf(v.All()))
+	require.InDelta(t, 8.0/3.0, MeanOf(v.All()), 1e-9)
+	mv, ok := MinOf(v.All())
+	require.True(t, ok)
+	require.Equal(t, int64(-2), mv)
+	mv, ok = MaxOf(v.Backward())
+	require.True(t, ok)
+	require.Equal(t, int64(6), mv)
+}
@@ -0,0 +1,68 @@
+package ringbuffer
+
+// Option configures the optional behaviour of a ring when it is created.
+// Each option documents the ring types that it applies to. Other ring types ignore it.
+type Option func(*options)
+
+type options struct {
+	noClear     bool
+	preallocate bool
+	fullPolicy  FullPolicy
+	zeroOnNext  bool
+}
+
+func makeOptions(opts []Option) options {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithoutClearing stops a RingT from setting the slot of an item to nil when the
+// item is removed by Next, NextMany or PopNewest. This saves a memory store per item,
+// but the ring keeps a reference to removed items until their slots are reused,
+// so the garbage collector cannot reclaim them. Use this only when the items
+// are long lived, or reachable from elsewhere anyway.
+func WithoutClearing() Option {
+	return func(o *options) {
+		o.noClear = true
+	}
+}
+
+// WithPreallocate makes a RingT or RingV allocate enough space for maxSize items when it is created,
+// instead of growing incrementally. This avoids the latency of growing the ring while
+// it fills up, at the cost of allocating memory that may never be used.
+func WithPreallocate() Option {
+	return func(o *options) {
+		o.preallocate = true
+	}
+}
+
+// WithZeroOnNext makes a RingP set the slot of an item to the zero value when the
+// item is removed by Next, PopNewest or DrainInto. Without this option, a RingP keeps
+// removed values in their slots until the slots are reused, so if T contains pointers
+// or slices, the garbage collector cannot reclaim the memory they reference.
+func WithZeroOnNext() Option {
+	return func(o *options) {
+		o.zeroOnNext = true
+	}
+}
+
+// FullPolicy determines what happens when an item is added to a full ring
+type FullPolicy int
+
+const (
+	FullDropOldest FullPolicy = iota // Erase the oldest item to make room for the new item. This is the default.
+	FullDropNewest                   // Erase the newest item to make room for the new item.
+	FullReject                       // Discard the new item, and leave the ring unchanged.
+	FullBlock                        // Wait until there is room for the new item. Rings that cannot wait treat this like FullReject.
+)
+
+// WithFullPolicy sets the behaviour of Add when a RingT, RingP, SyncRingT or SyncRingP is full.
+// Only SyncRingT can wait for room, so the other rings treat FullBlock like FullReject.
+func WithFullPolicy(policy FullPolicy) Option {
+	return func(o *options) {
+		o.fullPolicy = policy
+	}
+}
@@ -0,0 +1,49 @@
+package ringbuffer
+
+import "sync"
+
+// Pool is a pool of Rings, which avoids the cost of growing a new Ring for every request.
+// Pool is safe for use by multiple goroutines.
+type Pool struct {
+	initialSize int
+	maxSize     int
+	pool        sync.Pool
+}
+
+// NewPool creates a new pool of Rings.
+// Every Ring returned by Get has capacity for at least initialSize bytes.
+// When a Ring is returned to the pool, its storage is discarded if it is larger than maxSize.
+// If maxSize is zero, then the storage of returned Rings is always kept.
+func NewPool(initialSize, maxSize int) *Pool {
+	return &Pool{
+		initialSize: initialSize,
+		maxSize:     maxSize,
+	}
+}
+
+// Get returns an empty Ring with capacity for at least initialSize bytes
+func (p *Pool) Get() *Ring {
+	r, _ := p.pool.Get().(*Ring)
+	if r == nil {
+		r = &Ring{}
+	}
+	if len(r.data) <= p.initialSize {
+		size := DefaultSize
+		for size <= p.initialSize {
+			size *= 2
+		}
+		r.data = make([]byte, size)
+	}
+	return r
+}
+
+// Put resets r, detaches its Gauge, and returns it to the pool.
+// You must not use r after calling Put.
+func (p *Pool) Put(r *Ring) {
+	r.Reset()
+	r.gauge = nil // don't publish to the previous owner's gauge
+	if p.maxSize != 0 && len(r.data) > p.maxSize {
+		r.data = nil
+	}
+	p.pool.Put(r)
+}
@@ -0,0 +1,39 @@
+package ringbuffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPool(t *testing.T) {
+	p := NewPool(100, 1000)
+
+	r := p.Get()
+	require.Equal(t, 0, r.Len())
+	require.Equal(t, 128, len(r.data))
+	var g Gauge
+	r.SetGauge(&g)
+	r.Write(makeTruth()[:50])
+	p.Put(r)
+	require.Equal(t, 0, r.Len())
+	require.Equal(t, 128, len(r.data))
+	require.Nil(t, r.gauge)
+	// The next owner's writes are not published to the previous owner's gauge
+	gaugeLen := g.Len()
+	next := p.Get()
+	next.Write(makeTruth()[:10])
+	require.Equal(t, gaugeLen, g.Len())
+	p.Put(next)
+
+	// oversized rings are shrunk
+	r = p.Get()
+	r.Write(makeTruth())
+	p.Put(r)
+	require.Nil(t, r.data)
+
+	r = p.Get()
+	require.Equal(t, 0, r.Len())
+	require.GreaterOrEqual(t, len(r.data)-1, 100)
+	require.LessOrEqual(t, len(r.data), 1000)
+}
@@ -15,6 +15,7 @@ package ringbuffer
 
 import (
 	"io"
+	"iter"
 )
 
 // Start buffer at 64 bytes. This just seems like a reasonable minimum.
@@ -22,9 +23,10 @@ const DefaultSize = 64
 
 // The zero value for Ring is an empty buffer ready to use.
 type Ring struct {
-	head uint
-	tail uint
-	data []byte
+	head  uint
+	tail  uint
+	data  []byte
+	gauge *Gauge
 }
 
 // Return the number of unread bytes in the buffer
@@ -32,6 +34,20 @@ func (r *Ring) Len() int {
 	return int((r.head - r.tail) & r.mask())
 }
 
+// Reset discards all unread bytes, but keeps the backing storage for reuse
+func (r *Ring) Reset() {
+	r.head = 0
+	r.tail = 0
+	r.publish()
+}
+
+// SetGauge attaches a Gauge to the ring, which publishes Len() for other goroutines
+// to observe. Pass nil to detach the gauge.
+func (r *Ring) SetGauge(g *Gauge) {
+	r.gauge = g
+	r.publish()
+}
+
 // Grow the buffer sufficiently so that you can write numBytes into it.
 // The returned slice is not guaranteed to be large enough to hold numBytes. If
 // the slice is not large enough, then it means that the requested range falls off the edge
@@ -48,6 +64,7 @@ func (r *Ring) DirectWrite(numBytes int) []byte {
 	}
 	slice := r.data[int(r.head) : int(r.head)+numBytes]
 	r.head = (r.head + uint(numBytes)) & r.mask()
+	r.publish()
 	return slice
 }
 
@@ -70,6 +87,7 @@ func (r *Ring) DirectRead(numBytes int) []byte {
 	}
 	res := r.data[r.tail : r.tail+uint(numBytes)]
 	r.tail = (r.tail + uint(numBytes)) & r.mask()
+	r.publish()
 	return res
 }
 
@@ -99,6 +117,144 @@ func (r *Ring) Write(b []byte) (int, error) {
 	return len(b), nil
 }
 
+// WriteOwned writes b into the ring, and may take ownership of b as the
+// ring's backing storage.
+// If the ring is empty, and cap(b) is a power of 2 that is larger than len(b),
+// then b becomes the ring's storage and no bytes are copied. This makes it
+// possible to hand very large buffers to the ring in O(1).
+// Otherwise, WriteOwned behaves like Write.
+// Either way, the caller must not use b after calling WriteOwned.
+func (r *Ring) WriteOwned(b []byte) {
+	c := cap(b)
+	if r.Len() == 0 && (c&(c-1)) == 0 && c > len(b) {
+		r.data = b[:c]
+		r.tail = 0
+		r.head = uint(len(b))
+		r.publish()
+		return
+	}
+	r.Write(b)
+}
+
+// Split removes the first n unread bytes from the ring, and returns them as a new Ring.
+// If n is greater than or equal to Len(), then the new ring steals our backing storage,
+// and no bytes are copied. In this case, our ring is left empty, with no storage.
+func (r *Ring) Split(n int) *Ring {
+	if n >= r.Len() {
+		split := &Ring{
+			head: r.head,
+			tail: r.tail,
+			data: r.data,
+		}
+		r.head = 0
+		r.tail = 0
+		r.data = nil
+		r.publish()
+		return split
+	}
+	split := &Ring{}
+	for n > 0 {
+		b := r.DirectRead(n)
+		split.Write(b)
+		n -= len(b)
+	}
+	return split
+}
+
+// Append moves all of the unread bytes from src into our ring, leaving src empty.
+// If our ring is empty, then we adopt src's backing storage, and no bytes are copied.
+// In this case, src is left with no storage.
+func (r *Ring) Append(src *Ring) {
+	if src == r {
+		return
+	}
+	if r.Len() == 0 {
+		r.head = src.head
+		r.tail = src.tail
+		r.data = src.data
+		src.head = 0
+		src.tail = 0
+		src.data = nil
+		r.publish()
+		src.publish()
+		return
+	}
+	for src.Len() != 0 {
+		r.Write(src.DirectRead(src.Len()))
+	}
+}
+
+// Chunks returns an iterator over the unread bytes, in successive chunks of size bytes.
+// The final chunk may be shorter than size. The bytes are not consumed.
+// Chunks point directly into the ring buffer, except for a chunk that straddles the
+// end of the circular buffer, which is copied into a temporary buffer that is reused
+// for the duration of the iteration.
+// The ring must not be modified during iteration.
+func (r *Ring) Chunks(size int) iter.Seq[[]byte] {
+	if size < 1 {
+		panic("Chunk size must be at least 1")
+	}
+	return func(yield func([]byte) bool) {
+		var scratch []byte
+		remain := uint(r.Len())
+		pos := r.tail
+		for remain != 0 {
+			n := uint(size)
+			if n > remain {
+				n = remain
+			}
+			var chunk []byte
+			if pos+n <= r.end() {
+				chunk = r.data[pos : pos+n]
+			} else {
+				if scratch == nil {
+					scratch = make([]byte, size)
+				}
+				first := copy(scratch, r.data[pos:])
+				copy(scratch[first:n], r.data)
+				chunk = scratch[:n]
+			}
+			if !yield(chunk) {
+				return
+			}
+			pos = (pos + n) & r.mask()
+			remain -= n
+		}
+	}
+}
+
+// Copy unread bytes, starting at offset, into dst, without consuming them.
+// Returns the number of bytes copied.
+func (r *Ring) peek(dst []byte, offset int) int {
+	if offset >= r.Len() {
+		return 0
+	}
+	start := (r.tail + uint(offset)) & r.mask()
+	n := r.Len() - offset
+	if n > len(dst) {
+		n = len(dst)
+	}
+	first := copy(dst[:n], r.data[start:])
+	copy(dst[first:n], r.data)
+	return n
+}
+
+// Consume n unread bytes
+func (r *Ring) discard(n int) {
+	if n > r.Len() {
+		n = r.Len()
+	}
+	r.tail = (r.tail + uint(n)) & r.mask()
+	r.publish()
+}
+
+// Publish our length to our gauge, if we have one
+func (r *Ring) publish() {
+	if r.gauge != nil {
+		r.gauge.length.Store(int64(r.Len()))
+	}
+}
+
 // End of the buffer
 func (r *Ring) end() uint {
 	return uint(len(r.data))
@@ -162,3 +162,121 @@ func TestGrowOverEdge(t *testing.T) {
 		t.Error("non-trivial growth caused buffer corruption (wrong content)")
 	}
 }
+
+func TestWriteOwned(t *testing.T) {
+	truth := makeTruth()
+
+	// Adopted, because the ring is empty and cap is a power of 2
+	r := &Ring{}
+	b := make([]byte, 1000, 1024)
+	copy(b, truth)
+	r.WriteOwned(b)
+	if &r.data[0] != &b[0] {
+		t.Error("Expected WriteOwned to adopt the buffer")
+	}
+	verifyNonMutate(t, "adopted", truth[:1000], r)
+	r.Write(truth[1000:1010])
+	verifyNonMutate(t, "adopted + write", truth[:1010], r)
+
+	// Copied, because cap is not a power of 2
+	r = &Ring{}
+	b = make([]byte, 1000)
+	copy(b, truth)
+	r.WriteOwned(b)
+	if &r.data[0] == &b[0] {
+		t.Error("Expected WriteOwned to copy the buffer")
+	}
+	verifyNonMutate(t, "copied", truth[:1000], r)
+
+	// Copied, because the ring is not empty
+	r = &Ring{}
+	r.Write(truth[:10])
+	b = make([]byte, 100, 128)
+	copy(b, truth[10:])
+	r.WriteOwned(b)
+	verifyNonMutate(t, "not empty", truth[:110], r)
+}
+
+func TestSplit(t *testing.T) {
+	truth := makeTruth()
+
+	// Split over the wraparound point
+	r := &Ring{}
+	r.Write(truth[:100])
+	r.DirectRead(90)
+	r.Write(truth[100:150])
+	a := r.Split(45)
+	verifyNonMutate(t, "split head", truth[90:135], a)
+	verifyNonMutate(t, "split remainder", truth[135:150], r)
+
+	// Split everything, which steals the storage
+	data := &r.data[0]
+	b := r.Split(1000)
+	if &b.data[0] != data {
+		t.Error("Expected Split to steal storage")
+	}
+	verifyNonMutate(t, "split all", truth[135:150], b)
+	if r.Len() != 0 || r.data != nil {
+		t.Error("Expected Split to leave an empty ring")
+	}
+	r.Write(truth[:10])
+	verifyNonMutate(t, "reuse after split", truth[:10], r)
+}
+
+func TestAppend(t *testing.T) {
+	truth := makeTruth()
+
+	// Append into an empty ring, which steals the storage
+	a := &Ring{}
+	b := &Ring{}
+	b.Write(truth[:100])
+	data := &b.data[0]
+	a.Append(b)
+	if &a.data[0] != data {
+		t.Error("Expected Append to steal storage")
+	}
+	verifyNonMutate(t, "steal", truth[:100], a)
+	verifyNonMutate(t, "steal src", nil, b)
+
+	// Append into a non-empty ring, with src wrapped around
+	b.Write(truth[:100])
+	b.DirectRead(90)
+	b.Write(truth[100:150])
+	a.Append(b)
+	verifyNonMutate(t, "copy", append(append([]byte{}, truth[:100]...), truth[90:150]...), a)
+	verifyNonMutate(t, "copy src", nil, b)
+}
+
+func TestChunks(t *testing.T) {
+	truth := makeTruth()
+	r := &Ring{}
+	r.Write(truth[:100])
+	r.DirectRead(90)
+	r.Write(truth[100:150])
+	// tail is at 90, head is at 22, so chunks of 7 straddle the edge at 128
+
+	for _, size := range []int{1, 7, 38, 60, 100} {
+		var all []byte
+		for chunk := range r.Chunks(size) {
+			if len(chunk) > size {
+				t.Errorf("Chunk too large (%v > %v)", len(chunk), size)
+			}
+			all = append(all, chunk...)
+		}
+		if !bytes.Equal(all, truth[90:150]) {
+			t.Errorf("Chunks(%v) returned invalid data", size)
+		}
+		verifyNonMutate(t, "chunks", truth[90:150], r)
+	}
+
+	n := 0
+	for range r.Chunks(10) {
+		n++
+		if n == 2 {
+			break
+		}
+	}
+	if n != 2 {
+		t.Error("Chunks did not stop early")
+	}
+}
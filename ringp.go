@@ -1,5 +1,11 @@
 package ringbuffer
 
+import (
+	"encoding/binary"
+	"fmt"
+	"iter"
+)
+
 // Example
 //
 // length: 8
@@ -19,21 +25,55 @@ type RingP[T any] struct {
 	mask  uint // mask = len(items) - 1
 	tail  uint // read from tail
 	head  uint // write into head
+
+	onOverwrite func(item T)
+	stats       RingPStats
+	zeroOnNext  bool       // see WithZeroOnNext
+	policy      FullPolicy // see WithFullPolicy
+}
+
+// RingPStats are counters of the activity of a RingP
+type RingPStats struct {
+	Adds       uint64 // Total number of items added by Add, CommitSlot and AddSlice
+	Overwrites uint64 // Total number of items erased because the ring was full
+	Rejections uint64 // Total number of items discarded by Add because the ring was full (see FullReject)
 }
 
 // NewRingP creates a new ring buffer with the specified maximum size.
 // sizePlus1 must be a power of 2.
 // The maximum number of elements in the ring is sizePlus1 - 1
-func NewRingP[T any](sizePlus1 int) RingP[T] {
+func NewRingP[T any](sizePlus1 int, opts ...Option) RingP[T] {
 	if (sizePlus1&(sizePlus1-1)) != 0 || sizePlus1 < 2 {
 		panic("sizePlus1 must be a power of 2, and minimum 2")
 	}
+	o := makeOptions(opts)
 	return RingP[T]{
-		items: make([]T, sizePlus1),
-		mask:  uint(sizePlus1) - 1,
-		tail:  0,
-		head:  0,
+		items:      make([]T, sizePlus1),
+		mask:       uint(sizePlus1) - 1,
+		tail:       0,
+		head:       0,
+		zeroOnNext: o.zeroOnNext,
+		policy:     o.fullPolicy,
+	}
+}
+
+// NewRingPCap creates a new ring buffer that can hold at least capacity elements.
+// Because the size of the ring must be a power of 2, the actual capacity is rounded
+// up to the next 2^N - 1. The capacity must be at least 1.
+func NewRingPCap[T any](capacity int, opts ...Option) RingP[T] {
+	return NewRingP[T](sizePlus1ForCapacity(capacity), opts...)
+}
+
+// Returns the smallest power of 2 that is at least capacity + 1
+func sizePlus1ForCapacity(capacity int) int {
+	if capacity < 1 {
+		panic("capacity must be at least 1")
 	}
+	sizePlus1 := 2
+	for sizePlus1-1 < capacity {
+		sizePlus1 *= 2
+	}
+	return sizePlus1
 }
 
 // Capacity is the capacity of the ring buffer, which is 2^N - 1
@@ -41,6 +81,35 @@ func (r *RingP[T]) Capacity() int {
 	return int(r.mask)
 }
 
+// Resize reallocates the ring, so that it can hold at least capacity elements.
+// As for NewRingPCap, the actual capacity is rounded up to the next 2^N - 1.
+// The newest min(Len(), capacity) elements are kept, and any older elements are erased.
+// The capacity must be at least 1.
+func (r *RingP[T]) Resize(capacity int) {
+	sizePlus1 := sizePlus1ForCapacity(capacity)
+	n := min(r.Len(), capacity)
+	r.tail = (r.tail + uint(r.Len()-n)) & r.mask
+	items := make([]T, sizePlus1)
+	first, second := r.oldest(n)
+	copy(items[copy(items, first):], second)
+	r.items = items
+	r.mask = uint(sizePlus1) - 1
+	r.tail = 0
+	r.head = uint(n)
+}
+
+// OnOverwrite sets a function that is called with the oldest item,
+// when Add erases it to make room for a new item.
+// Pass nil to remove the callback.
+func (r *RingP[T]) OnOverwrite(f func(item T)) {
+	r.onOverwrite = f
+}
+
+// Stats returns the counters of the ring's activity since it was created
+func (r *RingP[T]) Stats() RingPStats {
+	return r.stats
+}
+
 // IsFull returns true if the ring buffer is full, and adding
 // another item will cause the oldest item to be popped.
 func (r *RingP[T]) IsFull() bool {
@@ -61,9 +130,81 @@ func (r *RingP[T]) Next() T {
 	t := r.tail
 	r.tail = (r.tail + 1) & r.mask
 	item := r.items[t]
+	if r.zeroOnNext {
+		var zero T
+		r.items[t] = zero
+	}
 	return item
 }
 
+// Drain removes all items from the ring, and returns them in a newly allocated slice,
+// from oldest to newest.
+func (r *RingP[T]) Drain() []T {
+	items := make([]T, r.Len())
+	r.NextInto(items)
+	return items
+}
+
+// DrainInto removes up to len(dst) items from the ring, and stores them in dst,
+// from oldest to newest. Returns the number of items stored in dst.
+// DrainInto is the same as NextInto.
+func (r *RingP[T]) DrainInto(dst []T) int {
+	return r.NextInto(dst)
+}
+
+// NextInto removes up to len(dst) items from the ring, and stores them in dst,
+// from oldest to newest. Returns the number of items stored in dst.
+// This is equivalent to calling Next() that many times, but it copies
+// the items in at most two chunks.
+func (r *RingP[T]) NextInto(dst []T) int {
+	n := min(r.Len(), len(dst))
+	first, second := r.oldest(n)
+	copy(dst[copy(dst, first):], second)
+	r.discard(n)
+	return n
+}
+
+// CopyTo copies up to len(dst) items from the ring into dst, from oldest to newest,
+// without removing them from the ring. Returns the number of items copied.
+func (r *RingP[T]) CopyTo(dst []T) int {
+	n := min(r.Len(), len(dst))
+	first, second := r.oldest(n)
+	copy(dst[copy(dst, first):], second)
+	return n
+}
+
+// CopyFrom adds the items in src to the ring, from oldest to newest, overwriting
+// the oldest items as needed. If src is longer than Capacity(), then the ring
+// holds only the last Capacity() items of src. CopyFrom is AddSlice without the
+// count of erased items, so stats, OnOverwrite and the FullPolicy apply as for AddSlice.
+func (r *RingP[T]) CopyFrom(src []T) {
+	r.AddSlice(src)
+}
+
+// PopNewest removes and returns the most recently added item.
+// If the ring is empty, PopNewest returns the zero object and false.
+func (r *RingP[T]) PopNewest() (T, bool) {
+	if r.Len() == 0 {
+		var zero T
+		return zero, false
+	}
+	r.head = (r.head - 1) & r.mask
+	item := r.items[r.head]
+	if r.zeroOnNext {
+		var zero T
+		r.items[r.head] = zero
+	}
+	return item, true
+}
+
+// Clear removes all items from the ring, and sets every slot to the zero value,
+// so that the garbage collector can reclaim anything that the items referenced.
+func (r *RingP[T]) Clear() {
+	clear(r.items)
+	r.tail = 0
+	r.head = 0
+}
+
 // Peek returns the Tail+i element from the buffer.
 // Peek(0) returns the same result as Next(), except that Peek() does not
 // change any state.
@@ -78,13 +219,264 @@ func (r *RingP[T]) Peek(i int) T {
 	return r.items[j]
 }
 
+// PeekLast returns the most recently added item, or the zero object if the ring is empty
+func (r *RingP[T]) PeekLast() T {
+	if r.Len() == 0 {
+		var zero T
+		return zero
+	}
+	return r.items[(r.head-1)&r.mask]
+}
+
+// FromNewest returns the item that was added i items before the newest item,
+// so FromNewest(0) is the same as PeekLast(). Returns the zero object if i is
+// not less than Len(). Unlike Peek, the index of an item does not change when
+// the oldest item is erased, but it does change when a new item is added.
+//
+// This suits rollback, where a game or simulation keeps its state for each of
+// the last N frames, and must return to the state from k frames ago:
+//
+//	states := NewRingPCap[State](N)
+//	// each frame
+//	states.Add(state)
+//	// to roll back k frames
+//	state = states.FromNewest(k)
+//	for range k {
+//		states.PopNewest()
+//	}
+func (r *RingP[T]) FromNewest(i int) T {
+	ui := uint(i)
+	if ui >= uint(r.Len()) {
+		var zero T
+		return zero
+	}
+	return r.items[(r.head-1-ui)&r.mask]
+}
+
+// All returns an iterator over the items in the ring, from oldest to newest.
+// The ring must not be modified during iteration.
+func (r *RingP[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		n := uint(r.Len())
+		for i := uint(0); i < n; i++ {
+			if !yield(r.items[(r.tail+i)&r.mask]) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the items in the ring, from newest to oldest.
+// The ring must not be modified during iteration.
+func (r *RingP[T]) Backward() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		n := uint(r.Len())
+		for i := uint(1); i <= n; i++ {
+			if !yield(r.items[(r.head-i)&r.mask]) {
+				return
+			}
+		}
+	}
+}
+
 // Add an item to the buffer.
-// If the buffer is full, erase the oldest item.
-func (r *RingP[T]) Add(item T) {
+// If the buffer is full, then the ring's FullPolicy decides what happens.
+// By default, the oldest item is erased to make room. Add returns the item
+// that was erased, or the new item if it was rejected, and true.
+// Otherwise, Add returns the zero value and false.
+func (r *RingP[T]) Add(item T) (evicted T, wasEvicted bool) {
+	*r.ReserveSlot() = item
+	return r.CommitSlot()
+}
+
+// TryAdd adds an item to the buffer, and returns true.
+// If the buffer is full, then TryAdd returns false, and the buffer is not modified,
+// regardless of the ring's FullPolicy.
+func (r *RingP[T]) TryAdd(item T) bool {
 	if r.IsFull() {
-		// erase oldest item
-		r.Next()
+		return false
+	}
+	r.Add(item)
+	return true
+}
+
+// ReserveSlot returns a pointer to the slot that the next item will be written into.
+// Fill in the slot, and then call CommitSlot to add it to the buffer.
+// This avoids copying large items through Add's parameter.
+// The head slot is never occupied, so filling it in doesn't disturb any existing items,
+// even if the buffer is full. The slot may hold stale data from an earlier item.
+// The pointer is only valid until the next call that modifies the ring.
+func (r *RingP[T]) ReserveSlot() *T {
+	return &r.items[r.head]
+}
+
+// CommitSlot adds the item that was written into the slot returned by ReserveSlot.
+// If the buffer is full, then the ring's FullPolicy decides what happens,
+// in the same way as Add.
+func (r *RingP[T]) CommitSlot() (evicted T, wasEvicted bool) {
+	if r.IsFull() {
+		switch r.policy {
+		case FullDropOldest:
+			evicted = r.Next()
+		case FullDropNewest:
+			// Move the new item into the newest item's slot. The head doesn't move.
+			newest := (r.head - 1) & r.mask
+			evicted = r.items[newest]
+			r.items[newest] = r.items[r.head]
+			if r.zeroOnNext {
+				var zero T
+				r.items[r.head] = zero
+			}
+			r.head = newest
+		default:
+			r.stats.Rejections++
+			evicted = r.items[r.head]
+			if r.zeroOnNext {
+				var zero T
+				r.items[r.head] = zero
+			}
+			return evicted, true
+		}
+		wasEvicted = true
+		r.stats.Overwrites++
+		if r.onOverwrite != nil {
+			r.onOverwrite(evicted)
+		}
 	}
-	r.items[r.head] = item
 	r.head = (r.head + 1) & r.mask
+	r.stats.Adds++
+	return
+}
+
+// AddSlice adds all of the items in src to the buffer, from first to last.
+// If the buffer doesn't have enough space, then the oldest items are erased,
+// and if src is longer than Capacity(), then only the last Capacity() items
+// of src are added. Returns the number of items that were erased, or skipped.
+// This is equivalent to calling Add() for each item in src, but it copies
+// the items in at most two chunks. If an OnOverwrite callback is set, then
+// AddSlice falls back to calling Add() for each item, so that the callback
+// sees every erased item. Likewise, if the ring's FullPolicy is not FullDropOldest,
+// then AddSlice calls Add() for each item, and the result includes rejected items.
+func (r *RingP[T]) AddSlice(src []T) (evicted int) {
+	if r.onOverwrite != nil || r.policy != FullDropOldest {
+		for _, item := range src {
+			if _, wasEvicted := r.Add(item); wasEvicted {
+				evicted++
+			}
+		}
+		return
+	}
+	r.stats.Adds += uint64(len(src))
+	if len(src) > r.Capacity() {
+		evicted = len(src) - r.Capacity()
+		src = src[evicted:]
+	}
+	if drop := r.Len() + len(src) - r.Capacity(); drop > 0 {
+		r.discard(drop)
+		evicted += drop
+	}
+	n := copy(r.items[r.head:], src)
+	copy(r.items, src[n:])
+	r.head = (r.head + uint(len(src))) & r.mask
+	r.stats.Overwrites += uint64(evicted)
+	return
+}
+
+// MarshalBinary encodes the ring's size and items into a compact little-endian blob.
+// T must be a fixed-size type, as defined by encoding/binary: a fixed-size number
+// such as int32 or float64 (but not int or uint), or an array or struct that
+// contains only fixed-size types. Padding and blank fields are encoded as zeros.
+// OnOverwrite and the options that the ring was created with are not encoded.
+func (r RingP[T]) MarshalBinary() ([]byte, error) {
+	size, err := fixedSize[T]()
+	if err != nil {
+		return nil, err
+	}
+	n := r.Len()
+	buf := make([]byte, 0, 8+size*n)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(r.items)))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(n))
+	first, second := r.oldest(n)
+	if buf, err = binary.Append(buf, binary.LittleEndian, first); err != nil {
+		return nil, err
+	}
+	if buf, err = binary.Append(buf, binary.LittleEndian, second); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary replaces the contents of the ring with a blob produced by MarshalBinary.
+// The ring keeps its current capacity, and an error is returned if the blob holds
+// more items than that. If the ring has no capacity yet (e.g. a zero RingP), then
+// it is allocated with just enough capacity for the items in the blob, rounded up
+// to the next 2^N - 1. The size that was encoded is not trusted, so a corrupt blob
+// can't make us allocate a huge buffer. OnOverwrite and any options that the ring
+// was created with are preserved.
+func (r *RingP[T]) UnmarshalBinary(data []byte) error {
+	size, err := fixedSize[T]()
+	if err != nil {
+		return err
+	}
+	if len(data) < 8 {
+		return fmt.Errorf("ringbuffer: binary data is too short")
+	}
+	sizePlus1 := int(binary.LittleEndian.Uint32(data))
+	n := int(binary.LittleEndian.Uint32(data[4:]))
+	if (sizePlus1&(sizePlus1-1)) != 0 || sizePlus1 < 2 || n >= sizePlus1 {
+		return fmt.Errorf("ringbuffer: invalid size %v with %v items", sizePlus1, n)
+	}
+	if len(data)-8 != size*n {
+		return fmt.Errorf("ringbuffer: expected %v bytes of items, but got %v", size*n, len(data)-8)
+	}
+	newSize := len(r.items)
+	if newSize == 0 {
+		newSize = sizePlus1ForCapacity(max(n, 1))
+	} else if n > r.Capacity() {
+		return fmt.Errorf("ringbuffer: %v items do not fit into a ring with capacity %v", n, r.Capacity())
+	}
+	items := make([]T, newSize)
+	if _, err := binary.Decode(data[8:], binary.LittleEndian, items[:n]); err != nil {
+		return err
+	}
+	r.items = items
+	r.mask = uint(newSize) - 1
+	r.tail = 0
+	r.head = uint(n)
+	return nil
+}
+
+// Returns the encoded size of T, or an error if T is not a fixed-size type.
+// We measure [1]T instead of T, because binary.Size of a nil slice is zero,
+// which would let a slice type such as []byte slip through.
+func fixedSize[T any]() (int, error) {
+	var one [1]T
+	size := binary.Size(one)
+	if size < 0 {
+		return 0, fmt.Errorf("ringbuffer: %T is not a fixed-size type", one[0])
+	}
+	return size, nil
+}
+
+// Remove the n oldest items from the ring.
+// n must not be more than Len().
+func (r *RingP[T]) discard(n int) {
+	if r.zeroOnNext {
+		first, second := r.oldest(n)
+		clear(first)
+		clear(second)
+	}
+	r.tail = (r.tail + uint(n)) & r.mask
+}
+
+// Returns the n oldest items as two segments of our array.
+// The items in first are older than the items in second.
+// n must not be more than Len().
+func (r *RingP[T]) oldest(n int) (first, second []T) {
+	first = r.items[r.tail:]
+	if len(first) > n {
+		first = first[:n]
+	}
+	second = r.items[:n-len(first)]
+	return
 }
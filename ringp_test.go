@@ -1,6 +1,8 @@
 package ringbuffer
 
 import (
+	"encoding/binary"
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -83,3 +85,382 @@ func TestRingP(t *testing.T) {
 	}
 	validate()
 }
+
+func TestRingPAddEvicted(t *testing.T) {
+	ring := NewRingP[pod](4)
+	for i := 0; i < 3; i++ {
+		evicted, wasEvicted := ring.Add(pod{id: i})
+		require.False(t, wasEvicted)
+		require.Equal(t, pod{}, evicted)
+	}
+	evicted, wasEvicted := ring.Add(pod{id: 3})
+	require.True(t, wasEvicted)
+	require.Equal(t, pod{id: 0}, evicted)
+}
+
+func TestRingPClear(t *testing.T) {
+	ring := NewRingP[*pod](4)
+	for i := 0; i < 6; i++ {
+		ring.Add(&pod{id: i})
+	}
+	ring.Clear()
+	require.Equal(t, 0, ring.Len())
+	require.Nil(t, ring.Next())
+	for _, item := range ring.items {
+		require.Nil(t, item)
+	}
+	ring.Add(&pod{id: 10})
+	require.Equal(t, 10, ring.Peek(0).id)
+}
+
+// Create a ring with items that wrap around the end of the buffer
+func makeRingP(sizePlus1, n int) (RingP[pod], []pod) {
+	ring := NewRingP[pod](sizePlus1)
+	var all []pod
+	for i := 0; i < n; i++ {
+		all = append(all, pod{id: i})
+		ring.Add(all[i])
+	}
+	if len(all) > sizePlus1-1 {
+		all = all[len(all)-(sizePlus1-1):]
+	}
+	return ring, all
+}
+
+func TestRingPPeekLast(t *testing.T) {
+	ring, all := makeRingP(8, 10)
+	require.Equal(t, all[len(all)-1], ring.PeekLast())
+	ring, all = makeRingP(8, 8)
+	require.Equal(t, all[len(all)-1], ring.PeekLast())
+	ring, _ = makeRingP(8, 0)
+	require.Equal(t, pod{}, ring.PeekLast())
+}
+
+func TestRingPPopNewest(t *testing.T) {
+	ring, all := makeRingP(8, 8)
+	for i := len(all) - 1; i >= 0; i-- {
+		item, ok := ring.PopNewest()
+		require.True(t, ok)
+		require.Equal(t, all[i], item)
+		require.Equal(t, i, ring.Len())
+	}
+	item, ok := ring.PopNewest()
+	require.False(t, ok)
+	require.Equal(t, pod{}, item)
+	ring.Add(pod{id: 100})
+	require.Equal(t, pod{id: 100}, ring.Next())
+}
+
+func TestRingPDrain(t *testing.T) {
+	for n := 0; n < 12; n++ {
+		ring, all := makeRingP(8, n)
+		require.Equal(t, len(all), len(ring.Drain()))
+		require.Equal(t, 0, ring.Len())
+	}
+	ring, all := makeRingP(8, 10)
+	require.Equal(t, all, ring.Drain())
+
+	for n := 0; n < 9; n++ {
+		ring, all = makeRingP(8, 10)
+		dst := make([]pod, n)
+		got := ring.DrainInto(dst)
+		require.Equal(t, min(n, 7), got)
+		require.Equal(t, all[:got], dst[:got])
+		require.Equal(t, 7-got, ring.Len())
+		if got < 7 {
+			require.Equal(t, all[got], ring.Peek(0))
+		}
+	}
+}
+
+func TestRingPAll(t *testing.T) {
+	ring, all := makeRingP(8, 10)
+	var items []pod
+	for item := range ring.All() {
+		items = append(items, item)
+	}
+	require.Equal(t, all, items)
+
+	items = nil
+	for item := range ring.Backward() {
+		items = append(items, item)
+	}
+	slices.Reverse(items)
+	require.Equal(t, all, items)
+
+	items = nil
+	for item := range ring.All() {
+		items = append(items, item)
+		break
+	}
+	require.Equal(t, all[:1], items)
+}
+
+func TestRingPOnOverwrite(t *testing.T) {
+	var overwritten []pod
+	ring := NewRingP[pod](4)
+	ring.OnOverwrite(func(item pod) {
+		overwritten = append(overwritten, item)
+	})
+	for i := 0; i < 5; i++ {
+		ring.Add(pod{id: i})
+	}
+	require.Equal(t, []pod{{id: 0}, {id: 1}}, overwritten)
+	ring.Next()
+	ring.Add(pod{id: 5})
+	require.Equal(t, []pod{{id: 0}, {id: 1}}, overwritten)
+}
+
+func TestRingPWithZeroOnNext(t *testing.T) {
+	for _, zero := range []bool{false, true} {
+		var opts []Option
+		if zero {
+			opts = append(opts, WithZeroOnNext())
+		}
+		ring := NewRingP[*pod](8, opts...)
+		for i := 0; i < 6; i++ {
+			ring.Add(&pod{id: i})
+		}
+		ring.Next()
+		ring.PopNewest()
+		ring.DrainInto(make([]*pod, 2))
+		require.Equal(t, 2, ring.Len())
+		nonNil := 0
+		for _, item := range ring.items {
+			if item != nil {
+				nonNil++
+			}
+		}
+		if zero {
+			require.Equal(t, 2, nonNil)
+		} else {
+			require.Equal(t, 6, nonNil)
+		}
+	}
+}
+
+func TestRingPResize(t *testing.T) {
+	ring, all := makeRingP(8, 10)
+	ring.Resize(15)
+	require.Equal(t, 15, ring.Capacity())
+	require.Equal(t, all, ring.Drain())
+
+	ring, all = makeRingP(8, 10)
+	ring.Resize(3)
+	require.Equal(t, 3, ring.Capacity())
+	require.Equal(t, all[4:], ring.Drain())
+
+	// The capacity is rounded up, but only the newest 2 items are kept
+	ring, all = makeRingP(8, 10)
+	ring.Resize(2)
+	require.Equal(t, 3, ring.Capacity())
+	require.Equal(t, all[5:], ring.Drain())
+
+	ring, _ = makeRingP(8, 10)
+	ring.Resize(7)
+	ring.Add(pod{id: 100})
+	require.Equal(t, pod{id: 100}, ring.PeekLast())
+	require.Panics(t, func() { ring.Resize(0) })
+}
+
+func TestNewRingPCap(t *testing.T) {
+	expect := map[int]int{1: 1, 2: 3, 3: 3, 4: 7, 7: 7, 8: 15, 100: 127}
+	for capacity, actual := range expect {
+		ring := NewRingPCap[pod](capacity)
+		require.Equal(t, actual, ring.Capacity())
+	}
+	require.Panics(t, func() { NewRingPCap[pod](0) })
+}
+
+func TestRingPAddSliceNextInto(t *testing.T) {
+	src := make([]pod, 20)
+	for i := range src {
+		src[i] = pod{id: i}
+	}
+	for _, n := range []int{0, 3, 7, 8, 20} {
+		for start := 0; start < 8; start++ {
+			// Compare against a ring that is filled one item at a time
+			ring, _ := makeRingP(8, start)
+			expect, _ := makeRingP(8, start)
+			evicted := 0
+			for _, item := range src[:n] {
+				if _, wasEvicted := expect.Add(item); wasEvicted {
+					evicted++
+				}
+			}
+			require.Equal(t, evicted, ring.AddSlice(src[:n]))
+			require.Equal(t, expect.Drain(), ring.Drain())
+		}
+	}
+
+	ring, all := makeRingP(8, 12)
+	dst := make([]pod, 4)
+	require.Equal(t, 4, ring.NextInto(dst))
+	require.Equal(t, all[:4], dst)
+	require.Equal(t, 3, ring.NextInto(dst))
+	require.Equal(t, all[4:], dst[:3])
+	require.Equal(t, 0, ring.NextInto(dst))
+}
+
+func TestRingPReserveSlot(t *testing.T) {
+	ring := NewRingP[pod](4)
+	for i := 0; i < 3; i++ {
+		ring.ReserveSlot().id = i
+		_, wasEvicted := ring.CommitSlot()
+		require.False(t, wasEvicted)
+	}
+	// The ring is full, but filling in the slot must not disturb the oldest item
+	ring.ReserveSlot().id = 3
+	require.Equal(t, 0, ring.Peek(0).id)
+	evicted, wasEvicted := ring.CommitSlot()
+	require.True(t, wasEvicted)
+	require.Equal(t, 0, evicted.id)
+	require.Equal(t, []pod{{1}, {2}, {3}}, ring.Drain())
+
+	// A rejected slot is zeroed, so that it doesn't keep the rejected item alive
+	pring := NewRingP[*pod](2, WithFullPolicy(FullReject), WithZeroOnNext())
+	pring.Add(&pod{id: 0})
+	*pring.ReserveSlot() = &pod{id: 1}
+	rejected, wasRejected := pring.CommitSlot()
+	require.True(t, wasRejected)
+	require.Equal(t, 1, rejected.id)
+	require.Nil(t, pring.items[pring.head])
+	require.Equal(t, 0, pring.Peek(0).id)
+}
+
+func TestRingPCopyToCopyFrom(t *testing.T) {
+	ring, all := makeRingP(8, 12)
+	dst := make([]pod, 10)
+	require.Equal(t, 7, ring.CopyTo(dst))
+	require.Equal(t, all, dst[:7])
+	require.Equal(t, 7, ring.Len())
+	require.Equal(t, 3, ring.CopyTo(dst[:3]))
+	require.Equal(t, all[:3], dst[:3])
+
+	// The oldest 2 items are overwritten
+	ring.CopyFrom([]pod{{100}, {101}})
+	require.Equal(t, append(append([]pod{}, all[2:7]...), pod{100}, pod{101}), ring.Drain())
+
+	src := make([]pod, 10)
+	for i := range src {
+		src[i] = pod{id: i}
+	}
+	ring.Add(pod{id: 200})
+	ring.CopyFrom(src)
+	require.Equal(t, src[3:], ring.Drain())
+	ring.CopyFrom(nil)
+	require.Equal(t, 0, ring.Len())
+
+	var overwritten []pod
+	ring.OnOverwrite(func(item pod) { overwritten = append(overwritten, item) })
+	ring.CopyFrom(src[:7])
+	ring.CopyFrom(src[7:])
+	require.Equal(t, src[:3], overwritten)
+	require.Equal(t, src[3:], ring.Drain())
+}
+
+func TestRingPMarshalBinary(t *testing.T) {
+	type sample struct {
+		Time  int64
+		Value float32
+	}
+	ring := NewRingP[sample](4)
+	for i := 0; i < 5; i++ {
+		ring.Add(sample{Time: int64(i), Value: float32(i) / 2})
+	}
+	data, err := ring.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, 8+3*12, len(data))
+
+	var loaded RingP[sample]
+	require.NoError(t, loaded.UnmarshalBinary(data))
+	require.Equal(t, 3, loaded.Capacity())
+	require.Equal(t, ring.Drain(), loaded.Drain())
+
+	require.Error(t, loaded.UnmarshalBinary(data[:len(data)-1]))
+	require.Error(t, loaded.UnmarshalBinary(data[:4]))
+
+	// A ring that already has a capacity keeps it, and rejects a blob that doesn't fit
+	big := NewRingP[sample](16)
+	require.NoError(t, big.UnmarshalBinary(data))
+	require.Equal(t, 15, big.Capacity())
+	require.Equal(t, 3, big.Len())
+	small := NewRingP[sample](2)
+	require.Error(t, small.UnmarshalBinary(data))
+
+	// A huge encoded size must not be allocated
+	header := binary.LittleEndian.AppendUint32(nil, 1<<30)
+	header = binary.LittleEndian.AppendUint32(header, 0)
+	var ints RingP[int64]
+	require.NoError(t, ints.UnmarshalBinary(header))
+	require.Equal(t, 1, ints.Capacity())
+	require.Equal(t, 0, ints.Len())
+
+	// pod has an int, which is not a fixed-size type
+	_, err = NewRingP[pod](4).MarshalBinary()
+	require.Error(t, err)
+
+	// A slice is not a fixed-size type, even when the ring is empty
+	_, err = NewRingP[[]byte](4).MarshalBinary()
+	require.Error(t, err)
+	bytesRing := NewRingP[[]byte](4)
+	bytesRing.Add([]byte{1, 2})
+	_, err = bytesRing.MarshalBinary()
+	require.Error(t, err)
+	require.Error(t, bytesRing.UnmarshalBinary(data))
+}
+
+func TestRingPStats(t *testing.T) {
+	ring, _ := makeRingP(4, 5)
+	require.Equal(t, RingPStats{Adds: 5, Overwrites: 2}, ring.Stats())
+	ring.AddSlice([]pod{{1}, {2}})
+	require.Equal(t, RingPStats{Adds: 7, Overwrites: 4}, ring.Stats())
+	ring.AddSlice(make([]pod, 5))
+	require.Equal(t, RingPStats{Adds: 12, Overwrites: 9}, ring.Stats())
+	ring.Next()
+	require.Equal(t, RingPStats{Adds: 12, Overwrites: 9}, ring.Stats())
+}
+
+func TestRingPFullPolicy(t *testing.T) {
+	ring := NewRingP[pod](4, WithFullPolicy(FullReject))
+	require.True(t, ring.TryAdd(pod{0}))
+	ring.AddSlice([]pod{{1}, {2}})
+	require.False(t, ring.TryAdd(pod{3}))
+	evicted, wasEvicted := ring.Add(pod{3})
+	require.True(t, wasEvicted)
+	require.Equal(t, pod{3}, evicted)
+	require.Equal(t, 1, ring.AddSlice([]pod{{4}}))
+	require.Equal(t, RingPStats{Adds: 3, Rejections: 2}, ring.Stats())
+	require.Equal(t, []pod{{0}, {1}, {2}}, ring.Drain())
+
+	var overwritten []pod
+	ring = NewRingP[pod](4, WithFullPolicy(FullDropNewest), WithZeroOnNext())
+	ring.OnOverwrite(func(item pod) { overwritten = append(overwritten, item) })
+	ring.AddSlice([]pod{{0}, {1}, {2}, {3}, {4}})
+	require.Equal(t, []pod{{2}, {3}}, overwritten)
+	require.Equal(t, pod{4}, ring.PeekLast())
+	require.Equal(t, []pod{{0}, {1}, {4}}, ring.Drain())
+	require.Equal(t, make([]pod, 4), ring.items)
+
+	// Default policy
+	ring = NewRingP[pod](2)
+	require.True(t, ring.TryAdd(pod{0}))
+	require.False(t, ring.TryAdd(pod{1}))
+	require.Equal(t, pod{0}, ring.Peek(0))
+}
+
+func TestRingPFromNewest(t *testing.T) {
+	ring, all := makeRingP(8, 10)
+	for i := 0; i < len(all); i++ {
+		require.Equal(t, all[len(all)-1-i], ring.FromNewest(i))
+	}
+	require.Equal(t, pod{}, ring.FromNewest(len(all)))
+	require.Equal(t, pod{}, ring.FromNewest(-1))
+
+	// Roll back 2 frames
+	state := ring.FromNewest(2)
+	ring.PopNewest()
+	ring.PopNewest()
+	require.Equal(t, state, ring.PeekLast())
+	require.Equal(t, all[len(all)-3], state)
+}
@@ -1,5 +1,12 @@
 package ringbuffer
 
+import (
+	"encoding/json"
+	"iter"
+	"slices"
+	"sort"
+)
+
 // Example
 //
 // length: 8
@@ -12,26 +19,46 @@ package ringbuffer
 
 // RingT is a generic ring buffer that holds pointers to a generic type T.
 // When popping an item from the tail of the ring, we set it's pointer to nil,
-// to ensure that the garbage collector can reclaim the memory for that item.
+// to ensure that the garbage collector can reclaim the memory for that item
+// (unless the ring was created WithoutClearing).
 type RingT[T any] struct {
 	items   []*T // len(items) is a power of 2.
 	mask    uint // mask = len(items) - 1
 	tail    uint // read from tail
 	head    uint // write into head
 	maxSize int
+	onEvict func(item *T)
+	stats   RingTStats
+	noClear bool       // see WithoutClearing
+	policy  FullPolicy // see WithFullPolicy
+}
+
+// RingTStats are counters of the activity of a RingT
+type RingTStats struct {
+	Adds       uint64 // Total number of items added
+	Evictions  uint64 // Total number of items erased because the ring was full, or shrunk by SetMaxSize
+	Rejections uint64 // Total number of items discarded by Add because the ring was full (see FullReject)
+	HighWater  int    // Largest number of items that the ring has held
 }
 
 // NewRingT creates a new ring buffer with the specified maximum size.
 // The maximum size must be at least 1.
 // The ring's underlying buffer is grown incrementally in powers of 2,
-// so the maxSize is not allocated up front.
-func NewRingT[T any](maxSize int) RingT[T] {
+// so the maxSize is not allocated up front, unless you pass WithPreallocate.
+func NewRingT[T any](maxSize int, opts ...Option) RingT[T] {
 	if maxSize < 1 {
 		panic("RingT size must be at least 1")
 	}
-	return RingT[T]{
+	o := makeOptions(opts)
+	r := RingT[T]{
 		maxSize: maxSize,
+		noClear: o.noClear,
+		policy:  o.fullPolicy,
 	}
+	if o.preallocate {
+		r.reserve(maxSize)
+	}
+	return r
 }
 
 // MaxSize is the maximum number of elements in the ring buffer
@@ -39,6 +66,32 @@ func (r *RingT[T]) MaxSize() int {
 	return r.maxSize
 }
 
+// SetMaxSize changes the maximum number of elements in the ring buffer.
+// If the ring holds more than maxSize items, the oldest items are erased.
+// The maximum size must be at least 1.
+func (r *RingT[T]) SetMaxSize(maxSize int) {
+	if maxSize < 1 {
+		panic("RingT size must be at least 1")
+	}
+	r.maxSize = maxSize
+	for r.Len() > maxSize {
+		r.evictOldest()
+	}
+}
+
+// OnEvict sets a function that is called for every item that the ring erases
+// without returning it to the caller. This happens when adding to a full ring,
+// when shrinking the ring with SetMaxSize, and when calling Clear, DropOldest or KeepLast.
+// Pass nil to remove the callback.
+func (r *RingT[T]) OnEvict(f func(item *T)) {
+	r.onEvict = f
+}
+
+// Stats returns counters of the ring's activity since it was created
+func (r *RingT[T]) Stats() RingTStats {
+	return r.stats
+}
+
 // IsFull returns true if the ring buffer is full, and adding
 // another item will cause the oldest item to be popped.
 func (r *RingT[T]) IsFull() bool {
@@ -58,10 +111,125 @@ func (r *RingT[T]) Next() *T {
 	t := r.tail
 	r.tail = (r.tail + 1) & r.mask
 	item := r.items[t]
-	r.items[t] = nil // erase item, so that the garbage collector can do it's job
+	if !r.noClear {
+		r.items[t] = nil // erase item, so that the garbage collector can do it's job
+	}
+	return item
+}
+
+// NextMany removes up to len(dst) items from the ring, and stores them in dst, in order.
+// Returns the number of items stored in dst.
+func (r *RingT[T]) NextMany(dst []*T) int {
+	n := min(r.Len(), len(dst))
+	first, second := r.oldest(n)
+	copy(dst, first)
+	copy(dst[len(first):], second)
+	if !r.noClear {
+		clear(first)
+		clear(second)
+	}
+	r.tail = (r.tail + uint(n)) & r.mask
+	return n
+}
+
+// PopNewest removes and returns the most recently added item, or nil if the ring is empty
+func (r *RingT[T]) PopNewest() *T {
+	if r.Len() == 0 {
+		return nil
+	}
+	r.head = (r.head - 1) & r.mask
+	item := r.items[r.head]
+	if !r.noClear {
+		r.items[r.head] = nil // erase item, so that the garbage collector can do it's job
+	}
+	return item
+}
+
+// DropOldest erases the n oldest items, and returns the number of items erased.
+func (r *RingT[T]) DropOldest(n int) int {
+	n = max(min(n, r.Len()), 0)
+	first, second := r.oldest(n)
+	if r.onEvict != nil {
+		for _, item := range first {
+			r.onEvict(item)
+		}
+		for _, item := range second {
+			r.onEvict(item)
+		}
+	}
+	r.discard(n)
+	return n
+}
+
+// KeepLast erases the oldest items, so that at most the n newest items remain.
+// Returns the number of items erased.
+func (r *RingT[T]) KeepLast(n int) int {
+	return r.DropOldest(r.Len() - n)
+}
+
+// RemoveAt removes and returns the Tail+i element from the buffer, or nil if i is out of range.
+// The items on the shorter side of i are shifted to close the gap.
+func (r *RingT[T]) RemoveAt(i int) *T {
+	n := r.Len()
+	if i < 0 || i >= n {
+		return nil
+	}
+	ui := uint(i)
+	item := r.items[(r.tail+ui)&r.mask]
+	if i < n/2 {
+		// shift older items forward
+		for j := ui; j > 0; j-- {
+			r.items[(r.tail+j)&r.mask] = r.items[(r.tail+j-1)&r.mask]
+		}
+		r.items[r.tail] = nil
+		r.tail = (r.tail + 1) & r.mask
+	} else {
+		// shift newer items back
+		for j := ui; j < uint(n-1); j++ {
+			r.items[(r.tail+j)&r.mask] = r.items[(r.tail+j+1)&r.mask]
+		}
+		r.head = (r.head - 1) & r.mask
+		r.items[r.head] = nil
+	}
 	return item
 }
 
+// RemoveIf removes all items for which remove returns true, and returns the number
+// of items removed. The order of the remaining items is preserved.
+func (r *RingT[T]) RemoveIf(remove func(item *T) bool) int {
+	n := uint(r.Len())
+	w := r.tail
+	for i := uint(0); i < n; i++ {
+		j := (r.tail + i) & r.mask
+		item := r.items[j]
+		if remove(item) {
+			continue
+		}
+		r.items[w] = item
+		w = (w + 1) & r.mask
+	}
+	removed := int((r.head - w) & r.mask)
+	for ; w != r.head; w = (w + 1) & r.mask {
+		r.items[w] = nil // erase item, so that the garbage collector can do it's job
+	}
+	r.head = (r.head - uint(removed)) & r.mask
+	return removed
+}
+
+// Clear removes all items from the ring.
+// The backing array is kept for reuse, but all of its slots are set to nil,
+// so that the garbage collector can reclaim the items.
+func (r *RingT[T]) Clear() {
+	if r.onEvict != nil {
+		for item := range r.All() {
+			r.onEvict(item)
+		}
+	}
+	clear(r.items)
+	r.tail = 0
+	r.head = 0
+}
+
 // Peek returns the Tail+i element from the buffer.
 // Peek(0) returns the same result as Next(), except that Peek() does not
 // change any state.
@@ -75,32 +243,414 @@ func (r *RingT[T]) Peek(i int) *T {
 	return r.items[j]
 }
 
+// ReplaceAt replaces the Tail+i element in the buffer with item, and returns
+// the element that was replaced. If i is out of range, then ReplaceAt does
+// nothing, and returns nil.
+func (r *RingT[T]) ReplaceAt(i int, item *T) *T {
+	if i < 0 || i >= r.Len() {
+		return nil
+	}
+	j := (r.tail + uint(i)) & r.mask
+	old := r.items[j]
+	r.items[j] = item
+	return old
+}
+
+// Swap swaps the Tail+i and Tail+j elements in the buffer.
+// Swap panics if i or j is out of range.
+func (r *RingT[T]) Swap(i, j int) {
+	n := r.Len()
+	if i < 0 || i >= n || j < 0 || j >= n {
+		panic("RingT.Swap index out of range")
+	}
+	a := (r.tail + uint(i)) & r.mask
+	b := (r.tail + uint(j)) & r.mask
+	r.items[a], r.items[b] = r.items[b], r.items[a]
+}
+
+// PeekLast returns the most recently added item, or nil if the ring is empty.
+func (r *RingT[T]) PeekLast() *T {
+	if r.Len() == 0 {
+		return nil
+	}
+	return r.items[(r.head-1)&r.mask]
+}
+
+// IndexOf returns the index (as used by Peek) of the oldest item for which match
+// returns true, or -1 if there is no such item.
+func (r *RingT[T]) IndexOf(match func(item *T) bool) int {
+	n := uint(r.Len())
+	for i := uint(0); i < n; i++ {
+		if match(r.items[(r.tail+i)&r.mask]) {
+			return int(i)
+		}
+	}
+	return -1
+}
+
+// Contains returns true if match returns true for any item in the ring
+func (r *RingT[T]) Contains(match func(item *T) bool) bool {
+	return r.IndexOf(match) != -1
+}
+
+// All returns an iterator over the items in the ring, from oldest to newest.
+// The ring must not be modified during iteration.
+func (r *RingT[T]) All() iter.Seq[*T] {
+	return func(yield func(*T) bool) {
+		n := uint(r.Len())
+		for i := uint(0); i < n; i++ {
+			if !yield(r.items[(r.tail+i)&r.mask]) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the items in the ring, from newest to oldest.
+// The ring must not be modified during iteration.
+func (r *RingT[T]) Backward() iter.Seq[*T] {
+	return func(yield func(*T) bool) {
+		n := uint(r.Len())
+		for i := uint(1); i <= n; i++ {
+			if !yield(r.items[(r.head-i)&r.mask]) {
+				return
+			}
+		}
+	}
+}
+
+// Do calls f for each item in the ring, from oldest to newest.
+// If f returns false, then iteration stops.
+// The ring must not be modified by f.
+func (r *RingT[T]) Do(f func(item *T) bool) {
+	n := uint(r.Len())
+	for i := uint(0); i < n; i++ {
+		if !f(r.items[(r.tail+i)&r.mask]) {
+			return
+		}
+	}
+}
+
+// Values returns a newly allocated slice of the items in the ring, from oldest to newest
+func (r *RingT[T]) Values() []*T {
+	values := make([]*T, r.Len())
+	first, second := r.Slices()
+	n := copy(values, first)
+	copy(values[n:], second)
+	return values
+}
+
+// Slices returns the items in the ring as two segments of the ring's backing array.
+// The items in first are older than the items in second, and each segment is ordered
+// from oldest to newest. If the items do not wrap around the end of the backing array,
+// then second is empty.
+// The slices point directly into the ring buffer, so they are only valid until the ring
+// is next modified, and they must not be modified.
+func (r *RingT[T]) Slices() (first, second []*T) {
+	if r.head >= r.tail {
+		return r.items[r.tail:r.head], nil
+	}
+	return r.items[r.tail:], r.items[:r.head]
+}
+
+// Clone returns an independent copy of the ring, which holds the same items.
+// The items themselves are not copied. See CloneFunc for a deep copy.
+func (r *RingT[T]) Clone() RingT[T] {
+	c := *r
+	c.items = append([]*T(nil), r.items...)
+	return c
+}
+
+// CloneFunc returns an independent copy of the ring, where each item
+// is the result of calling clone on the corresponding item in our ring.
+func (r *RingT[T]) CloneFunc(clone func(item *T) *T) RingT[T] {
+	c := r.Clone()
+	for i := uint(0); i < uint(c.Len()); i++ {
+		j := (c.tail + i) & c.mask
+		c.items[j] = clone(c.items[j])
+	}
+	return c
+}
+
+// Sort sorts the items in the ring, so that the oldest item is the least.
+// The sort is not guaranteed to be stable.
+func (r *RingT[T]) Sort(less func(a, b *T) bool) {
+	r.linearize()
+	items := r.items[:r.Len()]
+	sort.Slice(items, func(i, j int) bool {
+		return less(items[i], items[j])
+	})
+}
+
+// MarshalJSON encodes the items in the ring as a JSON array, from oldest to newest.
+// MarshalJSON has a value receiver, so that a RingT can be marshalled whether
+// or not it is addressable.
+func (r RingT[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Values())
+}
+
+// UnmarshalJSON replaces the contents of the ring with the items in a JSON array.
+// If the array holds more than MaxSize items, then only the newest items are kept.
+// If the ring is the zero value, then MaxSize becomes the length of the array.
+func (r *RingT[T]) UnmarshalJSON(data []byte) error {
+	var items []*T
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	if r.maxSize == 0 {
+		r.maxSize = max(len(items), 1)
+	}
+	if len(items) > r.maxSize {
+		items = items[len(items)-r.maxSize:]
+	}
+	r.Clear()
+	r.AddMany(items)
+	return nil
+}
+
 // Add an item to the buffer.
-// If the buffer is full, erase the oldest item.
-func (r *RingT[T]) Add(item *T) {
+// If the buffer is full, then the ring's FullPolicy decides what happens.
+// By default, the oldest item is erased to make room. Add returns the item
+// that was erased, or the new item if it was rejected. Otherwise, Add returns nil.
+func (r *RingT[T]) Add(item *T) (evicted *T) {
 	if r.Len() == r.maxSize {
-		// erase oldest item
-		r.Next()
+		switch r.policy {
+		case FullDropOldest:
+			evicted = r.evictOldest()
+		case FullDropNewest:
+			evicted = r.evictNewest()
+		default:
+			r.stats.Rejections++
+			return item
+		}
+	}
+
+	r.push(item)
+	return
+}
+
+// TryAdd adds an item to the buffer, and returns true.
+// If the buffer is full, then TryAdd returns false, and the buffer is not modified.
+func (r *RingT[T]) TryAdd(item *T) bool {
+	if r.Len() == r.maxSize {
+		return false
+	}
+	r.push(item)
+	return true
+}
+
+// AddMany adds items to the buffer, in order.
+// The result is the same as calling Add for each item, but the work
+// of growing the buffer and erasing old items is done once, and the
+// items are copied into the buffer in bulk.
+// Returns the number of items that were accepted. This is always len(items),
+// unless the ring's FullPolicy rejects items, in which case the items
+// that do not fit are rejected.
+func (r *RingT[T]) AddMany(items []*T) (accepted int) {
+	space := r.maxSize - r.Len()
+	switch r.policy {
+	case FullDropOldest:
+		if drop := len(items) - space; drop > 0 {
+			fromRing := min(drop, r.Len())
+			r.stats.Evictions += uint64(fromRing)
+			r.DropOldest(fromRing)
+			if drop -= fromRing; drop > 0 {
+				// The earliest new items would be erased by the later ones
+				r.stats.Adds += uint64(drop)
+				r.stats.Evictions += uint64(drop)
+				if r.onEvict != nil {
+					for _, item := range items[:drop] {
+						r.onEvict(item)
+					}
+				}
+				items = items[drop:]
+			}
+		}
+		r.pushMany(items)
+	case FullDropNewest:
+		n := min(space, len(items))
+		r.pushMany(items[:n])
+		if rest := items[n:]; len(rest) != 0 {
+			// Each remaining item would replace the newest item, so only the last one survives
+			newest := (r.head - 1) & r.mask
+			evicted := r.items[newest]
+			r.items[newest] = rest[len(rest)-1]
+			r.stats.Adds += uint64(len(rest))
+			r.stats.Evictions += uint64(len(rest))
+			if r.onEvict != nil {
+				r.onEvict(evicted)
+				for _, item := range rest[:len(rest)-1] {
+					r.onEvict(item)
+				}
+			}
+		}
+	default:
+		if len(items) > space {
+			r.stats.Rejections += uint64(len(items) - space)
+			r.pushMany(items[:space])
+			return space
+		}
+		r.pushMany(items)
+	}
+	return len(items)
+}
+
+// Merge moves all of the items in src to the end of our ring, in order, as if by AddMany.
+// If our ring is full, then our FullPolicy decides what happens. Items that we reject
+// are left in src. All other items are removed from src.
+func (r *RingT[T]) Merge(src *RingT[T]) {
+	if src == r {
+		return
+	}
+	first, second := src.Slices()
+	n := r.AddMany(first)
+	if n == len(first) {
+		n += r.AddMany(second)
 	}
+	src.discard(n)
+}
 
-	if len(r.items) == 0 || r.Len() == len(r.items)-1 {
-		// need to grow array
-		newSize := len(r.items) * 2
-		if newSize < 2 {
-			newSize = 2
+// PushFront inserts an item at the tail of the buffer, so that it becomes
+// the oldest item, and will be the next item returned by Next().
+// If the buffer is full, erase the newest item, and return it.
+// If the ring's FullPolicy is FullReject or FullBlock, then the new item is rejected
+// instead, and returned. Otherwise, return nil.
+func (r *RingT[T]) PushFront(item *T) (evicted *T) {
+	if r.Len() == r.maxSize {
+		if r.policy == FullReject || r.policy == FullBlock {
+			r.stats.Rejections++
+			return item
 		}
-		newItems := make([]*T, newSize, newSize)
-		n := r.Len()
-		for i := 0; i < n; i++ {
-			item := r.Next()
-			newItems[i] = item
+		evicted = r.evictNewest()
+	}
+	r.growIfFull()
+	r.tail = (r.tail - 1) & r.mask
+	r.items[r.tail] = item
+	r.countAdds(1)
+	return
+}
+
+// PopBack is the same as PopNewest. It exists to complete the deque API,
+// where Add/PushFront insert, and Next/PopBack remove.
+func (r *RingT[T]) PopBack() *T {
+	return r.PopNewest()
+}
+
+// Put an item that was removed by Next back at the tail, as if it had never been removed.
+// If the ring has filled up since then, then the item is the oldest, so it is erased,
+// just as Add would have erased it. In that case, OnEvict sees the item.
+func (r *RingT[T]) unpop(item *T) {
+	if r.Len() == r.maxSize {
+		r.stats.Evictions++
+		if r.onEvict != nil {
+			r.onEvict(item)
 		}
-		r.items = newItems
-		r.mask = uint(newSize) - 1
-		r.tail = 0
-		r.head = uint(n)
+		return
 	}
+	r.growIfFull()
+	r.tail = (r.tail - 1) & r.mask
+	r.items[r.tail] = item
+}
+
+// Remove the n oldest items, without returning them. n must not be more than Len().
+func (r *RingT[T]) discard(n int) {
+	first, second := r.oldest(n)
+	clear(first)
+	clear(second)
+	r.tail = (r.tail + uint(n)) & r.mask
+}
+
+// Returns the n oldest items as two segments of our array, like Slices.
+// n must not be more than Len().
+func (r *RingT[T]) oldest(n int) (first, second []*T) {
+	first = r.items[r.tail:]
+	if len(first) > n {
+		first = first[:n]
+	}
+	second = r.items[:n-len(first)]
+	return
+}
+
+// Rotate our array in place, so that the tail is at index 0
+func (r *RingT[T]) linearize() {
+	if r.tail == 0 {
+		return
+	}
+	n := r.Len()
+	slices.Reverse(r.items[:r.tail])
+	slices.Reverse(r.items[r.tail:])
+	slices.Reverse(r.items)
+	r.tail = 0
+	r.head = uint(n)
+}
 
+// Add an item at the head, which must not be full
+func (r *RingT[T]) push(item *T) {
+	r.growIfFull()
 	r.items[r.head] = item
 	r.head = (r.head + 1) & r.mask
+	r.countAdds(1)
+}
+
+// Add items at the head, which must have room for them
+func (r *RingT[T]) pushMany(items []*T) {
+	r.reserve(r.Len() + len(items))
+	n := copy(r.items[r.head:], items)
+	copy(r.items, items[n:])
+	r.head = (r.head + uint(len(items))) & r.mask
+	r.countAdds(len(items))
+}
+
+// Update our statistics after adding n items
+func (r *RingT[T]) countAdds(n int) {
+	r.stats.Adds += uint64(n)
+	r.stats.HighWater = max(r.stats.HighWater, r.Len())
+}
+
+// Erase the oldest item, and return it
+func (r *RingT[T]) evictOldest() *T {
+	r.stats.Evictions++
+	item := r.Next()
+	if r.onEvict != nil {
+		r.onEvict(item)
+	}
+	return item
+}
+
+// Erase the newest item, and return it
+func (r *RingT[T]) evictNewest() *T {
+	r.stats.Evictions++
+	item := r.PopNewest()
+	if r.onEvict != nil {
+		r.onEvict(item)
+	}
+	return item
+}
+
+// Grow our array if there is no space to store another item
+func (r *RingT[T]) growIfFull() {
+	r.reserve(r.Len() + 1)
+}
+
+// Grow our array so that it can hold at least n items
+func (r *RingT[T]) reserve(n int) {
+	// The +1 here is because we can only store len(r.items)-1 items.
+	if n+1 <= len(r.items) {
+		return
+	}
+	newSize := len(r.items)
+	if newSize < 2 {
+		newSize = 2
+	}
+	for newSize < n+1 {
+		newSize *= 2
+	}
+	newItems := make([]*T, newSize)
+	count := r.Len()
+	first, second := r.Slices()
+	copy(newItems[copy(newItems, first):], second)
+	r.items = newItems
+	r.mask = uint(newSize) - 1
+	r.tail = 0
+	r.head = uint(count)
 }
@@ -1,6 +1,9 @@
 package ringbuffer
 
 import (
+	"encoding/json"
+	"slices"
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -82,3 +85,557 @@ func TestRingT(t *testing.T) {
 	}
 	validate()
 }
+
+// Create a ring with items that wrap around the end of the buffer
+func makeRingT(maxSize, n int) (RingT[obj], []*obj) {
+	ring := NewRingT[obj](maxSize)
+	var all []*obj
+	for i := 0; i < n; i++ {
+		o := &obj{id: i}
+		all = append(all, o)
+		ring.Add(o)
+	}
+	if len(all) > maxSize {
+		all = all[len(all)-maxSize:]
+	}
+	return ring, all
+}
+
+func TestRingTAll(t *testing.T) {
+	ring, expect := makeRingT(5, 9)
+	var actual []*obj
+	for item := range ring.All() {
+		actual = append(actual, item)
+	}
+	require.Equal(t, expect, actual)
+
+	actual = nil
+	for item := range ring.All() {
+		actual = append(actual, item)
+		if len(actual) == 2 {
+			break
+		}
+	}
+	require.Equal(t, expect[:2], actual)
+
+	empty := NewRingT[obj](3)
+	for range empty.All() {
+		t.Fatal("Expected no items")
+	}
+}
+
+func TestRingTDo(t *testing.T) {
+	ring, expect := makeRingT(5, 9)
+	var actual []*obj
+	ring.Do(func(item *obj) bool {
+		actual = append(actual, item)
+		return true
+	})
+	require.Equal(t, expect, actual)
+
+	actual = nil
+	ring.Do(func(item *obj) bool {
+		actual = append(actual, item)
+		return len(actual) < 3
+	})
+	require.Equal(t, expect[:3], actual)
+}
+
+func TestRingTValues(t *testing.T) {
+	ring, expect := makeRingT(5, 9)
+	require.Equal(t, expect, ring.Values())
+	ring, expect = makeRingT(5, 3)
+	require.Equal(t, expect, ring.Values())
+	empty := NewRingT[obj](3)
+	require.Equal(t, []*obj{}, empty.Values())
+}
+
+func TestRingTClear(t *testing.T) {
+	ring, _ := makeRingT(5, 9)
+	size := len(ring.items)
+	ring.Clear()
+	require.Equal(t, 0, ring.Len())
+	require.Nil(t, ring.Next())
+	require.Equal(t, size, len(ring.items))
+	for _, item := range ring.items {
+		require.Nil(t, item)
+	}
+	o := &obj{id: 1}
+	ring.Add(o)
+	require.Equal(t, []*obj{o}, ring.Values())
+}
+
+func TestRingTPeekLast(t *testing.T) {
+	ring, expect := makeRingT(5, 9)
+	require.Equal(t, expect[len(expect)-1], ring.PeekLast())
+	ring, expect = makeRingT(5, 8)
+	require.Equal(t, expect[len(expect)-1], ring.PeekLast())
+	empty := NewRingT[obj](3)
+	require.Nil(t, empty.PeekLast())
+}
+
+func TestRingTPopNewest(t *testing.T) {
+	ring, expect := makeRingT(5, 8)
+	for len(expect) != 0 {
+		require.Equal(t, expect[len(expect)-1], ring.PopNewest())
+		expect = expect[:len(expect)-1]
+		require.Equal(t, expect, ring.Values())
+	}
+	require.Nil(t, ring.PopNewest())
+	for _, item := range ring.items {
+		require.Nil(t, item)
+	}
+}
+
+func TestRingTDeque(t *testing.T) {
+	ring := NewRingT[obj](4)
+	var expect []*obj
+	for i := 0; i < 4; i++ {
+		o := &obj{id: i}
+		ring.PushFront(o)
+		expect = append([]*obj{o}, expect...)
+		require.Equal(t, expect, ring.Values())
+	}
+
+	// When full, PushFront erases the newest item
+	o := &obj{id: 10}
+	ring.PushFront(o)
+	expect = append([]*obj{o}, expect[:3]...)
+	require.Equal(t, expect, ring.Values())
+
+	// When full, Add erases the oldest item
+	o = &obj{id: 11}
+	ring.Add(o)
+	expect = append(expect[1:], o)
+	require.Equal(t, expect, ring.Values())
+
+	require.Equal(t, expect[3], ring.PopBack())
+	require.Equal(t, expect[0], ring.Next())
+	require.Equal(t, expect[1:3], ring.Values())
+}
+
+func TestRingTSetMaxSize(t *testing.T) {
+	ring, expect := makeRingT(6, 9)
+	ring.SetMaxSize(8)
+	require.Equal(t, expect, ring.Values())
+	ring.SetMaxSize(3)
+	require.Equal(t, expect[3:], ring.Values())
+	require.True(t, ring.IsFull())
+	o := &obj{id: 100}
+	ring.Add(o)
+	require.Equal(t, append(expect[4:], o), ring.Values())
+	require.Panics(t, func() { ring.SetMaxSize(0) })
+}
+
+func TestRingTOnEvict(t *testing.T) {
+	var evicted []*obj
+	ring := NewRingT[obj](3)
+	ring.OnEvict(func(item *obj) {
+		evicted = append(evicted, item)
+	})
+	var all []*obj
+	for i := 0; i < 5; i++ {
+		all = append(all, &obj{id: i})
+		ring.Add(all[i])
+	}
+	require.Equal(t, all[:2], evicted)
+
+	evicted = nil
+	front := &obj{id: 100}
+	ring.PushFront(front)
+	require.Equal(t, []*obj{all[4]}, evicted)
+
+	// items removed by Next are not evicted
+	evicted = nil
+	ring.Next()
+	require.Nil(t, evicted)
+
+	ring.Add(all[0])
+	ring.SetMaxSize(1)
+	require.Equal(t, []*obj{all[2], all[3]}, evicted)
+
+	evicted = nil
+	ring.Clear()
+	require.Equal(t, []*obj{all[0]}, evicted)
+}
+
+func TestRingTAddMany(t *testing.T) {
+	var all []*obj
+	for i := 0; i < 30; i++ {
+		all = append(all, &obj{id: i})
+	}
+
+	for _, policy := range []FullPolicy{FullDropOldest, FullDropNewest, FullReject} {
+		for maxSize := 1; maxSize < 10; maxSize++ {
+			for start := 0; start < 10; start++ {
+				for n := 0; n < 12; n++ {
+					// compare AddMany against individual calls to Add
+					var evictA, evictB []*obj
+					a, _ := makeRingT(maxSize, start)
+					b, _ := makeRingT(maxSize, start)
+					a.policy = policy
+					b.policy = policy
+					a.OnEvict(func(item *obj) { evictA = append(evictA, item) })
+					b.OnEvict(func(item *obj) { evictB = append(evictB, item) })
+					a.AddMany(all[:n])
+					for _, item := range all[:n] {
+						b.Add(item)
+					}
+					require.Equal(t, b.Values(), a.Values())
+					require.Equal(t, evictB, evictA)
+					require.Equal(t, b.Stats(), a.Stats())
+				}
+			}
+		}
+	}
+}
+
+func TestRingTNextMany(t *testing.T) {
+	for n := 0; n < 7; n++ {
+		ring, expect := makeRingT(5, 9)
+		dst := make([]*obj, n)
+		got := ring.NextMany(dst)
+		want := n
+		if want > len(expect) {
+			want = len(expect)
+		}
+		require.Equal(t, want, got)
+		require.Equal(t, expect[:want], dst[:got])
+		require.Equal(t, expect[want:], ring.Values())
+		nonNil := 0
+		for _, item := range ring.items {
+			if item != nil {
+				nonNil++
+			}
+		}
+		require.Equal(t, ring.Len(), nonNil)
+	}
+	empty := NewRingT[obj](3)
+	require.Equal(t, 0, empty.NextMany(make([]*obj, 3)))
+}
+
+func TestRingTRemoveIf(t *testing.T) {
+	ring, all := makeRingT(7, 12)
+	var expect []*obj
+	for _, item := range all {
+		if item.id%3 != 0 {
+			expect = append(expect, item)
+		}
+	}
+	removed := ring.RemoveIf(func(item *obj) bool { return item.id%3 == 0 })
+	require.Equal(t, len(all)-len(expect), removed)
+	require.Equal(t, expect, ring.Values())
+	nonNil := 0
+	for _, item := range ring.items {
+		if item != nil {
+			nonNil++
+		}
+	}
+	require.Equal(t, len(expect), nonNil)
+
+	require.Equal(t, 0, ring.RemoveIf(func(item *obj) bool { return false }))
+	require.Equal(t, len(expect), ring.RemoveIf(func(item *obj) bool { return true }))
+	require.Equal(t, 0, ring.Len())
+}
+
+func TestRingTRemoveAt(t *testing.T) {
+	for i := -1; i < 8; i++ {
+		ring, all := makeRingT(7, 12)
+		removed := ring.RemoveAt(i)
+		if i < 0 || i >= len(all) {
+			require.Nil(t, removed)
+			require.Equal(t, all, ring.Values())
+			continue
+		}
+		require.Equal(t, all[i], removed)
+		expect := append(append([]*obj{}, all[:i]...), all[i+1:]...)
+		require.Equal(t, expect, ring.Values())
+		nonNil := 0
+		for _, item := range ring.items {
+			if item != nil {
+				nonNil++
+			}
+		}
+		require.Equal(t, len(expect), nonNil)
+	}
+}
+
+func TestRingTIndexOf(t *testing.T) {
+	ring, all := makeRingT(7, 12)
+	for i, item := range all {
+		id := item.id
+		require.Equal(t, i, ring.IndexOf(func(item *obj) bool { return item.id == id }))
+		require.True(t, ring.Contains(func(item *obj) bool { return item.id == id }))
+	}
+	require.Equal(t, -1, ring.IndexOf(func(item *obj) bool { return item.id == 0 }))
+	require.False(t, ring.Contains(func(item *obj) bool { return item.id == 0 }))
+}
+
+func TestRingTClone(t *testing.T) {
+	ring, all := makeRingT(7, 12)
+	c := ring.Clone()
+	require.Equal(t, all, c.Values())
+	require.Equal(t, ring.MaxSize(), c.MaxSize())
+	c.Next()
+	c.Add(&obj{id: 100})
+	require.Equal(t, all, ring.Values())
+
+	d := ring.CloneFunc(func(item *obj) *obj {
+		clone := *item
+		return &clone
+	})
+	require.Equal(t, ring.Len(), d.Len())
+	for i := 0; i < ring.Len(); i++ {
+		require.Equal(t, *ring.Peek(i), *d.Peek(i))
+		require.NotSame(t, ring.Peek(i), d.Peek(i))
+	}
+}
+
+func TestRingTSort(t *testing.T) {
+	for n := 0; n < 12; n++ {
+		ring, all := makeRingT(7, n)
+		expect := append([]*obj{}, all...)
+		sort.Slice(expect, func(i, j int) bool { return expect[i].id > expect[j].id })
+		ring.Sort(func(a, b *obj) bool { return a.id > b.id })
+		require.Equal(t, expect, ring.Values())
+		o := &obj{id: 100}
+		ring.Add(o)
+		require.Equal(t, o, ring.PeekLast())
+	}
+}
+
+func TestRingTBackward(t *testing.T) {
+	ring, all := makeRingT(5, 9)
+	var actual []*obj
+	for item := range ring.Backward() {
+		actual = append(actual, item)
+	}
+	expect := append([]*obj{}, all...)
+	slices.Reverse(expect)
+	require.Equal(t, expect, actual)
+
+	actual = nil
+	for item := range ring.Backward() {
+		actual = append(actual, item)
+		break
+	}
+	require.Equal(t, expect[:1], actual)
+}
+
+func TestRingTStats(t *testing.T) {
+	ring, _ := makeRingT(5, 9)
+	require.Equal(t, RingTStats{Adds: 9, Evictions: 4, HighWater: 5}, ring.Stats())
+	ring.Next()
+	ring.Next()
+	ring.AddMany(make([]*obj, 8))
+	require.Equal(t, RingTStats{Adds: 17, Evictions: 10, HighWater: 5}, ring.Stats())
+	ring.PushFront(&obj{})
+	ring.SetMaxSize(3)
+	require.Equal(t, RingTStats{Adds: 18, Evictions: 13, HighWater: 5}, ring.Stats())
+}
+
+type jsonObj struct {
+	ID int
+}
+
+func TestRingTJSON(t *testing.T) {
+	ring := NewRingT[jsonObj](3)
+	for i := 0; i < 5; i++ {
+		ring.Add(&jsonObj{ID: i})
+	}
+	b, err := json.Marshal(ring)
+	require.NoError(t, err)
+	require.Equal(t, `[{"ID":2},{"ID":3},{"ID":4}]`, string(b))
+
+	state := struct {
+		Recent RingT[jsonObj]
+	}{}
+	require.NoError(t, json.Unmarshal([]byte(`{"Recent":[{"ID":2},{"ID":3},{"ID":4}]}`), &state))
+	require.Equal(t, 3, state.Recent.MaxSize())
+	require.Equal(t, ring.Values(), state.Recent.Values())
+
+	small := NewRingT[jsonObj](2)
+	require.NoError(t, json.Unmarshal(b, &small))
+	require.Equal(t, ring.Values()[1:], small.Values())
+
+	require.Error(t, json.Unmarshal([]byte(`{}`), &small))
+}
+
+func TestRingTWithoutClearing(t *testing.T) {
+	ring := NewRingT[obj](4, WithoutClearing())
+	a := &obj{id: 1}
+	b := &obj{id: 2}
+	c := &obj{id: 3}
+	ring.AddMany([]*obj{a, b, c})
+	require.Equal(t, a, ring.Next())
+	require.Equal(t, c, ring.PopNewest())
+	require.Equal(t, 1, ring.NextMany(make([]*obj, 2)))
+	require.Equal(t, 0, ring.Len())
+	// the items are still referenced by the ring
+	require.Equal(t, []*obj{a, b, c, nil}, ring.items)
+}
+
+func TestRingTAddEvicted(t *testing.T) {
+	ring := NewRingT[obj](2)
+	a := &obj{id: 1}
+	b := &obj{id: 2}
+	c := &obj{id: 3}
+	require.Nil(t, ring.Add(a))
+	require.Nil(t, ring.Add(b))
+	require.Equal(t, a, ring.Add(c))
+	require.Equal(t, c, ring.PushFront(a))
+	require.Equal(t, []*obj{a, b}, ring.Values())
+}
+
+func TestRingTWithPreallocate(t *testing.T) {
+	for maxSize := 1; maxSize < 10; maxSize++ {
+		ring := NewRingT[obj](maxSize, WithPreallocate())
+		size := len(ring.items)
+		require.GreaterOrEqual(t, size-1, maxSize)
+		for i := 0; i < maxSize*2; i++ {
+			ring.Add(&obj{id: i})
+		}
+		require.Equal(t, size, len(ring.items))
+		require.Equal(t, maxSize, ring.Len())
+	}
+}
+
+func TestRingTSlices(t *testing.T) {
+	for n := 0; n < 12; n++ {
+		ring, all := makeRingT(7, n)
+		first, second := ring.Slices()
+		require.Equal(t, len(all), len(first)+len(second))
+		for i, item := range first {
+			require.Equal(t, all[i], item)
+		}
+		for i, item := range second {
+			require.Equal(t, all[len(first)+i], item)
+		}
+	}
+	ring, _ := makeRingT(5, 9)
+	_, second := ring.Slices()
+	require.NotEmpty(t, second)
+}
+
+func TestRingTTryAdd(t *testing.T) {
+	ring := NewRingT[obj](2)
+	a := &obj{id: 1}
+	b := &obj{id: 2}
+	require.True(t, ring.TryAdd(a))
+	require.True(t, ring.TryAdd(b))
+	require.False(t, ring.TryAdd(&obj{id: 3}))
+	require.Equal(t, []*obj{a, b}, ring.Values())
+	require.Equal(t, RingTStats{Adds: 2, HighWater: 2}, ring.Stats())
+}
+
+func TestRingTFullPolicy(t *testing.T) {
+	var all []*obj
+	for i := 0; i < 10; i++ {
+		all = append(all, &obj{id: i})
+	}
+
+	ring := NewRingT[obj](3, WithFullPolicy(FullDropNewest))
+	ring.AddMany(all[:3])
+	require.Equal(t, all[2], ring.Add(all[3]))
+	require.Equal(t, []*obj{all[0], all[1], all[3]}, ring.Values())
+	require.Equal(t, 2, ring.AddMany(all[4:6]))
+	require.Equal(t, []*obj{all[0], all[1], all[5]}, ring.Values())
+	require.Equal(t, uint64(3), ring.Stats().Evictions)
+
+	for _, policy := range []FullPolicy{FullReject, FullBlock} {
+		ring = NewRingT[obj](3, WithFullPolicy(policy))
+		require.Equal(t, 2, ring.AddMany(all[:2]))
+		require.Equal(t, 1, ring.AddMany(all[2:4]))
+		require.Equal(t, all[4], ring.Add(all[4]))
+		require.Equal(t, all[5], ring.PushFront(all[5]))
+		require.Equal(t, all[:3], ring.Values())
+		require.Equal(t, RingTStats{Adds: 3, Rejections: 3, HighWater: 3}, ring.Stats())
+	}
+
+	// compare AddMany against individual calls to Add
+	for _, policy := range []FullPolicy{FullDropOldest, FullDropNewest, FullReject} {
+		for start := 0; start < 5; start++ {
+			for n := 0; n < 8; n++ {
+				a := NewRingT[obj](4, WithFullPolicy(policy))
+				b := NewRingT[obj](4, WithFullPolicy(policy))
+				a.AddMany(all[:start])
+				b.AddMany(all[:start])
+				a.AddMany(all[start : start+n])
+				for _, item := range all[start : start+n] {
+					b.Add(item)
+				}
+				require.Equal(t, b.Values(), a.Values())
+				require.Equal(t, b.Stats(), a.Stats())
+			}
+		}
+	}
+}
+
+func TestRingTDropOldest(t *testing.T) {
+	for n := -1; n < 9; n++ {
+		var evicted []*obj
+		ring, all := makeRingT(7, 12)
+		ring.OnEvict(func(item *obj) { evicted = append(evicted, item) })
+		drop := max(min(n, len(all)), 0)
+		require.Equal(t, drop, ring.DropOldest(n))
+		require.Equal(t, all[drop:], ring.Values())
+		require.Equal(t, all[:drop], append([]*obj{}, evicted...))
+		nonNil := 0
+		for _, item := range ring.items {
+			if item != nil {
+				nonNil++
+			}
+		}
+		require.Equal(t, ring.Len(), nonNil)
+	}
+
+	ring, all := makeRingT(7, 12)
+	require.Equal(t, 5, ring.KeepLast(2))
+	require.Equal(t, all[5:], ring.Values())
+	require.Equal(t, 0, ring.KeepLast(5))
+	require.Equal(t, all[5:], ring.Values())
+	require.Equal(t, 2, ring.KeepLast(0))
+	require.Equal(t, 0, ring.Len())
+}
+
+func TestRingTMerge(t *testing.T) {
+	a, allA := makeRingT(7, 12)
+	b, allB := makeRingT(5, 9)
+	var evictedB []*obj
+	b.OnEvict(func(item *obj) { evictedB = append(evictedB, item) })
+	a.SetMaxSize(20)
+	a.Merge(&b)
+	require.Equal(t, append(append([]*obj{}, allA...), allB...), a.Values())
+	require.Equal(t, 0, b.Len())
+	require.Nil(t, evictedB)
+
+	// Merge into a ring that is too small
+	a, allA = makeRingT(7, 12)
+	b, allB = makeRingT(5, 9)
+	a.Merge(&b)
+	all := append(append([]*obj{}, allA...), allB...)
+	require.Equal(t, all[len(all)-7:], a.Values())
+	require.Equal(t, 0, b.Len())
+
+	// Rejected items are left in src
+	a = NewRingT[obj](7, WithFullPolicy(FullReject))
+	a.AddMany(allA[:4])
+	b, allB = makeRingT(5, 9)
+	a.Merge(&b)
+	require.Equal(t, append(append([]*obj{}, allA[:4]...), allB[:3]...), a.Values())
+	require.Equal(t, allB[3:], b.Values())
+}
+
+func TestRingTReplaceAt(t *testing.T) {
+	ring, all := makeRingT(5, 9)
+	o := &obj{id: 100}
+	require.Equal(t, all[3], ring.ReplaceAt(3, o))
+	require.Equal(t, o, ring.Peek(3))
+	require.Nil(t, ring.ReplaceAt(5, o))
+	require.Nil(t, ring.ReplaceAt(-1, o))
+	require.Equal(t, []*obj{all[0], all[1], all[2], o, all[4]}, ring.Values())
+
+	ring.Swap(0, 4)
+	require.Equal(t, []*obj{all[4], all[1], all[2], o, all[0]}, ring.Values())
+	require.Panics(t, func() { ring.Swap(0, 5) })
+}
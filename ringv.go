@@ -0,0 +1,153 @@
+package ringbuffer
+
+import "iter"
+
+// Example
+//
+// length: 8
+// tail:   1
+// head:   3
+// number of elements in buffer: 2
+//
+// 0      1      2      3      4      5      6      7
+//      tail          head
+
+// RingV is a generic ring buffer that holds values of a generic type T.
+// It has the same semantics as RingT: a maximum size, and an underlying buffer
+// that grows incrementally. But because RingV stores values instead of pointers,
+// adding an item does not require a separate allocation per item.
+// When popping an item from the tail of the ring, we set it's slot to the zero value,
+// to ensure that the garbage collector can reclaim anything that the item references.
+type RingV[T any] struct {
+	items   []T  // len(items) is a power of 2.
+	mask    uint // mask = len(items) - 1
+	tail    uint // read from tail
+	head    uint // write into head
+	maxSize int
+}
+
+// NewRingV creates a new ring buffer with the specified maximum size.
+// The maximum size must be at least 1.
+// The ring's underlying buffer is grown incrementally in powers of 2,
+// so the maxSize is not allocated up front, unless you pass WithPreallocate.
+func NewRingV[T any](maxSize int, opts ...Option) RingV[T] {
+	if maxSize < 1 {
+		panic("RingV size must be at least 1")
+	}
+	o := makeOptions(opts)
+	r := RingV[T]{
+		maxSize: maxSize,
+	}
+	if o.preallocate {
+		r.reserve(maxSize)
+	}
+	return r
+}
+
+// MaxSize is the maximum number of elements in the ring buffer
+func (r *RingV[T]) MaxSize() int {
+	return r.maxSize
+}
+
+// IsFull returns true if the ring buffer is full, and adding
+// another item will cause the oldest item to be popped.
+func (r *RingV[T]) IsFull() bool {
+	return r.Len() == r.maxSize
+}
+
+// Len returns the number of elements in the buffer
+func (r *RingV[T]) Len() int {
+	return int((r.head - r.tail) & r.mask)
+}
+
+// Next returns the next item in the ring, or the zero value if the ring is empty
+func (r *RingV[T]) Next() T {
+	var zero T
+	if r.Len() == 0 {
+		return zero
+	}
+	t := r.tail
+	r.tail = (r.tail + 1) & r.mask
+	item := r.items[t]
+	r.items[t] = zero // erase item, so that the garbage collector can do it's job
+	return item
+}
+
+// Peek returns the Tail+i element from the buffer, or the zero value if i is out of range.
+// Peek(0) returns the same result as Next(), except that Peek() does not
+// change any state.
+func (r *RingV[T]) Peek(i int) T {
+	length := (r.head - r.tail) & r.mask
+	ui := uint(i)
+	if ui >= length {
+		var zero T
+		return zero
+	}
+	j := (r.tail + ui) & r.mask
+	return r.items[j]
+}
+
+// All returns an iterator over the items in the ring, from oldest to newest.
+// The ring must not be modified during iteration.
+func (r *RingV[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		n := uint(r.Len())
+		for i := uint(0); i < n; i++ {
+			if !yield(r.items[(r.tail+i)&r.mask]) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the items in the ring, from newest to oldest.
+// The ring must not be modified during iteration.
+func (r *RingV[T]) Backward() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		n := uint(r.Len())
+		for i := uint(1); i <= n; i++ {
+			if !yield(r.items[(r.head-i)&r.mask]) {
+				return
+			}
+		}
+	}
+}
+
+// Add an item to the buffer.
+// If the buffer is full, erase the oldest item, and return it.
+func (r *RingV[T]) Add(item T) (evicted T, wasEvicted bool) {
+	if r.Len() == r.maxSize {
+		evicted, wasEvicted = r.Next(), true
+	}
+	r.reserve(r.Len() + 1)
+	r.items[r.head] = item
+	r.head = (r.head + 1) & r.mask
+	return
+}
+
+// Grow our array so that it can hold at least n items
+func (r *RingV[T]) reserve(n int) {
+	// The +1 here is because we can only store len(r.items)-1 items.
+	if n+1 <= len(r.items) {
+		return
+	}
+	newSize := len(r.items)
+	if newSize < 2 {
+		newSize = 2
+	}
+	for newSize < n+1 {
+		newSize *= 2
+	}
+	newItems := make([]T, newSize)
+	count := r.Len()
+	if r.head >= r.tail {
+		copy(newItems, r.items[r.tail:r.head])
+	} else {
+		first := copy(newItems, r.items[r.tail:])
+		copy(newItems[first:], r.items[:r.head])
+	}
+	r.items = newItems
+	r.mask = uint(newSize) - 1
+	r.tail = 0
+	r.head = uint(count)
+}
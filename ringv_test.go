@@ -0,0 +1,96 @@
+package ringbuffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRingV(t *testing.T) {
+	var val []pod
+	var ring RingV[pod]
+	var zero pod
+
+	nextID := 100
+
+	init := func(maxSize int) {
+		val = []pod{}
+		ring = NewRingV[pod](maxSize)
+	}
+
+	validate := func() {
+		require.Equal(t, len(val), ring.Len())
+		for i := 0; i < len(val); i++ {
+			require.Equal(t, val[i], ring.Peek(i))
+		}
+		// verify that Peek(<invalid index>) returns the zero value
+		invalidIndices := []int{-1, len(val), len(val) + 1}
+		for _, invalidI := range invalidIndices {
+			require.Equal(t, zero, ring.Peek(invalidI))
+		}
+	}
+
+	add := func() {
+		item := pod{
+			id: nextID,
+		}
+		nextID++
+		expectEvicted := len(val) == ring.MaxSize()
+		var expectItem pod
+		if expectEvicted {
+			expectItem = val[0]
+			val = val[1:]
+		}
+		val = append(val, item)
+		evicted, wasEvicted := ring.Add(item)
+		require.Equal(t, expectEvicted, wasEvicted)
+		require.Equal(t, expectItem, evicted)
+	}
+
+	chomp := func() {
+		expectEmpty := len(val) == 0
+		actual := ring.Next()
+		if expectEmpty {
+			require.Equal(t, zero, actual)
+		} else {
+			require.Equal(t, val[0], actual)
+			val = val[1:]
+		}
+	}
+
+	t.Logf("empty")
+	init(5)
+	validate()
+
+	t.Logf("add 1 at a time")
+	for maxSize := 1; maxSize < 7; maxSize++ {
+		init(maxSize)
+		for i := 0; i < 20; i++ {
+			add()
+			validate()
+		}
+	}
+
+	t.Logf("grow while wrapped")
+	init(20)
+	for i := 0; i < 3; i++ {
+		add()
+	}
+	for i := 0; i < 2; i++ {
+		chomp()
+	}
+	for i := 0; i < 15; i++ {
+		add()
+		validate()
+	}
+
+	init(5)
+	add()
+	add()
+	add()
+	validate()
+	for i := 0; i < 5; i++ {
+		chomp()
+	}
+	validate()
+}
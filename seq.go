@@ -0,0 +1,26 @@
+package ringbuffer
+
+import "iter"
+
+// ContainsValue returns true if seq yields a value that is equal to v.
+// seq is typically the All() iterator of a RingP or RingV.
+func ContainsValue[T comparable](seq iter.Seq[T], v T) bool {
+	for item := range seq {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// CountValue returns the number of values in seq that are equal to v.
+// seq is typically the All() iterator of a RingP or RingV.
+func CountValue[T comparable](seq iter.Seq[T], v T) int {
+	n := 0
+	for item := range seq {
+		if item == v {
+			n++
+		}
+	}
+	return n
+}
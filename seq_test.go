@@ -0,0 +1,27 @@
+package ringbuffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainsCountValue(t *testing.T) {
+	ring := NewRingP[int](4)
+	require.False(t, ContainsValue(ring.All(), 0))
+	ring.AddSlice([]int{1, 2, 1, 3})
+	// 1 is erased once
+	require.True(t, ContainsValue(ring.All(), 1))
+	require.Equal(t, 1, CountValue(ring.All(), 1))
+	require.Equal(t, 1, CountValue(ring.All(), 3))
+	require.False(t, ContainsValue(ring.All(), 4))
+	require.Equal(t, 0, CountValue(ring.All(), 4))
+
+	v := NewRingV[string](3)
+	for _, s := range []string{"a", "b", "a", "c"} {
+		v.Add(s)
+	}
+	require.False(t, ContainsValue(v.All(), "x"))
+	require.Equal(t, 1, CountValue(v.All(), "a"))
+	require.Equal(t, 1, CountValue(v.Backward(), "c"))
+}
@@ -0,0 +1,103 @@
+package ringbuffer
+
+import "iter"
+
+// SeqRingT is a ring buffer like RingT, which assigns a monotonically increasing
+// sequence number to every item that is added. Sequence numbers are stable, so a
+// consumer can remember the sequence number of the last item that it saw, and later
+// ask for everything after it, even if items have been erased in the meantime.
+// The first item is assigned sequence number 1, so 0 means "no item".
+// SeqRingT only supports adding at the head and removing from the tail, so that
+// the items in the ring always have consecutive sequence numbers.
+type SeqRingT[T any] struct {
+	ring    RingT[T]
+	nextSeq uint64 // sequence number of the next item to be added
+}
+
+// NewSeqRingT creates a new ring buffer with the specified maximum size.
+// The maximum size must be at least 1.
+func NewSeqRingT[T any](maxSize int) SeqRingT[T] {
+	return SeqRingT[T]{
+		ring:    NewRingT[T](maxSize),
+		nextSeq: 1,
+	}
+}
+
+// MaxSize is the maximum number of elements in the ring buffer
+func (r *SeqRingT[T]) MaxSize() int {
+	return r.ring.MaxSize()
+}
+
+// Len returns the number of elements in the buffer
+func (r *SeqRingT[T]) Len() int {
+	return r.ring.Len()
+}
+
+// Add an item to the buffer, and return its sequence number.
+// If the buffer is full, erase the oldest item.
+func (r *SeqRingT[T]) Add(item *T) uint64 {
+	r.ring.Add(item)
+	r.nextSeq++
+	return r.nextSeq - 1
+}
+
+// Next returns the next item in the ring, or nil if the ring is empty
+func (r *SeqRingT[T]) Next() *T {
+	return r.ring.Next()
+}
+
+// Peek returns the Tail+i element from the buffer, or nil if i is out of range
+func (r *SeqRingT[T]) Peek(i int) *T {
+	return r.ring.Peek(i)
+}
+
+// SeqOf returns the sequence number of the Tail+i element, or 0 if i is out of range
+func (r *SeqRingT[T]) SeqOf(i int) uint64 {
+	if i < 0 || i >= r.Len() {
+		return 0
+	}
+	return r.OldestSeq() + uint64(i)
+}
+
+// PeekSeq returns the item with sequence number seq, or nil if that item is not in the ring
+func (r *SeqRingT[T]) PeekSeq(seq uint64) *T {
+	if r.Len() == 0 || seq < r.OldestSeq() {
+		return nil
+	}
+	return r.ring.Peek(int(seq - r.OldestSeq()))
+}
+
+// OldestSeq returns the sequence number of the oldest item, or 0 if the ring is empty
+func (r *SeqRingT[T]) OldestSeq() uint64 {
+	if r.Len() == 0 {
+		return 0
+	}
+	return r.nextSeq - uint64(r.Len())
+}
+
+// NewestSeq returns the sequence number of the newest item, or 0 if the ring is empty
+func (r *SeqRingT[T]) NewestSeq() uint64 {
+	if r.Len() == 0 {
+		return 0
+	}
+	return r.nextSeq - 1
+}
+
+// After returns an iterator over the items whose sequence number is greater than seq,
+// from oldest to newest, along with their sequence numbers. If the items immediately
+// after seq have already been erased, then iteration starts at the oldest item.
+// The ring must not be modified during iteration.
+func (r *SeqRingT[T]) After(seq uint64) iter.Seq2[uint64, *T] {
+	return func(yield func(uint64, *T) bool) {
+		if seq >= r.nextSeq-1 {
+			// Nothing is newer than seq. This also avoids seq+1 wrapping around.
+			return
+		}
+		oldest := r.nextSeq - uint64(r.Len())
+		for s := max(seq+1, oldest); s < r.nextSeq; s++ {
+			if !yield(s, r.ring.Peek(int(s-oldest))) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package ringbuffer
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeqRingT(t *testing.T) {
+	ring := NewSeqRingT[obj](3)
+	require.Equal(t, uint64(0), ring.OldestSeq())
+	require.Equal(t, uint64(0), ring.NewestSeq())
+	require.Nil(t, ring.PeekSeq(0))
+
+	var all []*obj
+	for i := 0; i < 5; i++ {
+		all = append(all, &obj{id: i})
+		require.Equal(t, uint64(i+1), ring.Add(all[i]))
+	}
+	// sequence numbers 3, 4, 5 remain
+	require.Equal(t, uint64(3), ring.OldestSeq())
+	require.Equal(t, uint64(5), ring.NewestSeq())
+	require.Equal(t, uint64(4), ring.SeqOf(1))
+	require.Equal(t, uint64(0), ring.SeqOf(3))
+	require.Nil(t, ring.PeekSeq(2))
+	require.Equal(t, all[3], ring.PeekSeq(4))
+	require.Nil(t, ring.PeekSeq(6))
+
+	collect := func(seq uint64) (seqs []uint64, items []*obj) {
+		for s, item := range ring.After(seq) {
+			seqs = append(seqs, s)
+			items = append(items, item)
+		}
+		return
+	}
+	seqs, items := collect(3)
+	require.Equal(t, []uint64{4, 5}, seqs)
+	require.Equal(t, all[3:5], items)
+	seqs, _ = collect(0)
+	require.Equal(t, []uint64{3, 4, 5}, seqs)
+	seqs, _ = collect(5)
+	require.Nil(t, seqs)
+	seqs, _ = collect(math.MaxUint64)
+	require.Nil(t, seqs)
+
+	require.Equal(t, all[2], ring.Next())
+	require.Equal(t, uint64(4), ring.OldestSeq())
+	require.Equal(t, all[4], ring.Peek(1))
+	ring.Next()
+	ring.Next()
+	require.Equal(t, 0, ring.Len())
+	require.Equal(t, uint64(6), ring.Add(all[0]))
+	require.Equal(t, uint64(6), ring.OldestSeq())
+}
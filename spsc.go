@@ -0,0 +1,123 @@
+package ringbuffer
+
+import "sync/atomic"
+
+// Size of a CPU cache line, used to pad indices that are written by different
+// goroutines, so that they don't share a cache line (false sharing).
+const cacheLineSize = 64
+
+// SPSCRingP is a lock-free ring buffer that holds values of a generic type T.
+// It is safe for exactly one producer goroutine (calling TryAdd) and exactly one
+// consumer goroutine (calling TryNext) to use the ring concurrently.
+// Unlike RingP, adding to a full ring fails, instead of erasing the oldest item.
+// The head and tail indices live on separate cache lines, so that the producer
+// and consumer don't slow each other down.
+// When popping an item from the tail of the ring, we set it's slot to the zero value,
+// to ensure that the garbage collector can reclaim anything that the item references.
+type SPSCRingP[T any] struct {
+	items []T    // len(items) is a power of 2.
+	mask  uint64 // mask = len(items) - 1
+	_     [cacheLineSize]byte
+	head  atomic.Uint64 // write into head. Only modified by the producer.
+	_     [cacheLineSize - 8]byte
+	tail  atomic.Uint64 // read from tail. Only modified by the consumer.
+	_     [cacheLineSize - 8]byte
+}
+
+// NewSPSCRingP creates a new lock-free ring buffer that can hold capacity items.
+// capacity must be a power of 2.
+func NewSPSCRingP[T any](capacity int) *SPSCRingP[T] {
+	if (capacity&(capacity-1)) != 0 || capacity < 1 {
+		panic("capacity must be a power of 2")
+	}
+	return &SPSCRingP[T]{
+		items: make([]T, capacity),
+		mask:  uint64(capacity) - 1,
+	}
+}
+
+// Capacity is the maximum number of items in the ring
+func (r *SPSCRingP[T]) Capacity() int {
+	return len(r.items)
+}
+
+// Len returns the number of elements in the buffer.
+// If the ring is being modified concurrently, then the result is only a snapshot.
+func (r *SPSCRingP[T]) Len() int {
+	tail := r.tail.Load()
+	head := r.head.Load()
+	return int(head - tail)
+}
+
+// TryAdd adds an item to the buffer, and returns true.
+// If the buffer is full, then TryAdd returns false.
+// TryAdd may only be called by the producer goroutine.
+func (r *SPSCRingP[T]) TryAdd(item T) bool {
+	head := r.head.Load()
+	if head-r.tail.Load() == uint64(len(r.items)) {
+		return false
+	}
+	r.items[head&r.mask] = item
+	r.head.Store(head + 1)
+	return true
+}
+
+// TryNext returns the next item in the ring, and true.
+// If the ring is empty, TryNext returns the zero value and false.
+// TryNext may only be called by the consumer goroutine.
+func (r *SPSCRingP[T]) TryNext() (T, bool) {
+	var zero T
+	tail := r.tail.Load()
+	if tail == r.head.Load() {
+		return zero, false
+	}
+	i := tail & r.mask
+	item := r.items[i]
+	r.items[i] = zero // erase item, so that the garbage collector can do it's job
+	r.tail.Store(tail + 1)
+	return item, true
+}
+
+// SPSCRingT is a lock-free ring buffer that holds pointers to a generic type T.
+// It is safe for exactly one producer goroutine (calling TryAdd) and exactly one
+// consumer goroutine (calling TryNext) to use the ring concurrently.
+// Unlike RingT, the ring has a fixed capacity, and adding to a full ring fails,
+// instead of erasing the oldest item.
+// When popping an item from the tail of the ring, we set it's pointer to nil,
+// to ensure that the garbage collector can reclaim the memory for that item.
+type SPSCRingT[T any] struct {
+	ring *SPSCRingP[*T]
+}
+
+// NewSPSCRingT creates a new lock-free ring buffer that can hold capacity items.
+// capacity must be a power of 2.
+func NewSPSCRingT[T any](capacity int) *SPSCRingT[T] {
+	return &SPSCRingT[T]{
+		ring: NewSPSCRingP[*T](capacity),
+	}
+}
+
+// Capacity is the maximum number of items in the ring
+func (r *SPSCRingT[T]) Capacity() int {
+	return r.ring.Capacity()
+}
+
+// Len returns the number of elements in the buffer.
+// If the ring is being modified concurrently, then the result is only a snapshot.
+func (r *SPSCRingT[T]) Len() int {
+	return r.ring.Len()
+}
+
+// TryAdd adds an item to the buffer, and returns true.
+// If the buffer is full, then TryAdd returns false.
+// TryAdd may only be called by the producer goroutine.
+func (r *SPSCRingT[T]) TryAdd(item *T) bool {
+	return r.ring.TryAdd(item)
+}
+
+// TryNext returns the next item in the ring, or nil if the ring is empty.
+// TryNext may only be called by the consumer goroutine.
+func (r *SPSCRingT[T]) TryNext() *T {
+	item, _ := r.ring.TryNext()
+	return item
+}
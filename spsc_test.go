@@ -0,0 +1,69 @@
+package ringbuffer
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSPSCRingT(t *testing.T) {
+	ring := NewSPSCRingT[obj](4)
+	require.Nil(t, ring.TryNext())
+	for i := 0; i < 4; i++ {
+		require.True(t, ring.TryAdd(&obj{id: i}))
+	}
+	require.False(t, ring.TryAdd(&obj{id: 4}))
+	require.Equal(t, 4, ring.Len())
+	for i := 0; i < 4; i++ {
+		require.Equal(t, i, ring.TryNext().id)
+	}
+	require.Nil(t, ring.TryNext())
+	require.Panics(t, func() { NewSPSCRingT[obj](3) })
+}
+
+// Run with -race to verify the memory ordering
+func TestSPSCRingTConcurrent(t *testing.T) {
+	ring := NewSPSCRingT[obj](64)
+	const n = 100000
+	var wait sync.WaitGroup
+	wait.Add(1)
+	go func() {
+		defer wait.Done()
+		for i := 0; i < n; i++ {
+			for !ring.TryAdd(&obj{id: i}) {
+				runtime.Gosched()
+			}
+		}
+	}()
+	for i := 0; i < n; i++ {
+		item := ring.TryNext()
+		for item == nil {
+			runtime.Gosched()
+			item = ring.TryNext()
+		}
+		require.Equal(t, i, item.id)
+	}
+	wait.Wait()
+}
+
+func TestSPSCRingP(t *testing.T) {
+	ring := NewSPSCRingP[pod](4)
+	_, ok := ring.TryNext()
+	require.False(t, ok)
+	for i := 0; i < 4; i++ {
+		require.True(t, ring.TryAdd(pod{id: i}))
+	}
+	require.False(t, ring.TryAdd(pod{id: 4}))
+	require.Equal(t, 4, ring.Len())
+	for i := 0; i < 4; i++ {
+		item, ok := ring.TryNext()
+		require.True(t, ok)
+		require.Equal(t, i, item.id)
+	}
+	_, ok = ring.TryNext()
+	require.False(t, ok)
+	require.Equal(t, make([]pod, 4), ring.items)
+	require.Panics(t, func() { NewSPSCRingP[pod](0) })
+}
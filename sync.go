@@ -0,0 +1,286 @@
+package ringbuffer
+
+import (
+	"context"
+	"sync"
+)
+
+// SyncRingT is a RingT that is protected by a mutex, so that it can be shared
+// between goroutines, such as a producer and a consumer.
+type SyncRingT[T any] struct {
+	lock    sync.Mutex
+	changed notifier
+	ring    RingT[T]
+}
+
+// NewSyncRingT creates a new goroutine-safe ring buffer with the specified maximum size.
+// The maximum size must be at least 1.
+// The options are the same as for NewRingT.
+func NewSyncRingT[T any](maxSize int, opts ...Option) *SyncRingT[T] {
+	return &SyncRingT[T]{
+		ring: NewRingT[T](maxSize, opts...),
+	}
+}
+
+// Len returns the number of elements in the buffer
+func (r *SyncRingT[T]) Len() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.ring.Len()
+}
+
+// Next returns the next item in the ring, or nil if the ring is empty
+func (r *SyncRingT[T]) Next() *T {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.changed.broadcast()
+	return r.ring.Next()
+}
+
+// Peek returns the Tail+i element from the buffer.
+func (r *SyncRingT[T]) Peek(i int) *T {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.ring.Peek(i)
+}
+
+// Add an item to the buffer.
+// If the buffer is full, then the ring's FullPolicy decides what happens, as for RingT.Add.
+// If the FullPolicy is FullBlock, then Add waits until there is room for the item.
+func (r *SyncRingT[T]) Add(item *T) (evicted *T) {
+	if r.ring.policy == FullBlock {
+		r.PushWait(context.Background(), item)
+		return nil
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.changed.broadcast()
+	return r.ring.Add(item)
+}
+
+// PushWait adds an item to the buffer, waiting for space if the buffer is full.
+// Unlike Add, PushWait never erases items.
+// Returns ctx.Err() if ctx is done before the item could be added.
+func (r *SyncRingT[T]) PushWait(ctx context.Context, item *T) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for r.ring.IsFull() {
+		if err := r.changed.wait(ctx, &r.lock); err != nil {
+			return err
+		}
+	}
+	r.changed.broadcast()
+	r.ring.Add(item)
+	return nil
+}
+
+// PopWait returns the next item in the ring, waiting for an item if the buffer is empty.
+// Returns ctx.Err() if ctx is done before an item is available.
+func (r *SyncRingT[T]) PopWait(ctx context.Context) (*T, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for r.ring.Len() == 0 {
+		if err := r.changed.wait(ctx, &r.lock); err != nil {
+			return nil, err
+		}
+	}
+	r.changed.broadcast()
+	return r.ring.Next(), nil
+}
+
+// Drain starts a goroutine that removes items from the ring as they become
+// available, and sends them on the returned channel.
+// When ctx is done, the goroutine stops and closes the channel. If an item was
+// removed from the ring, but not yet received when ctx is done, then it is put
+// back at the front of the ring, so that the next consumer receives it.
+// If the ring filled up in the meantime, then that item is the oldest, so it is
+// erased, and passed to OnEvict.
+func (r *SyncRingT[T]) Drain(ctx context.Context) <-chan *T {
+	out := make(chan *T)
+	go func() {
+		defer close(out)
+		for {
+			item, err := r.PopWait(ctx)
+			if err != nil {
+				return
+			}
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				r.lock.Lock()
+				r.ring.unpop(item)
+				r.changed.broadcast()
+				r.lock.Unlock()
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Feed adds every item received from in to the ring, using Add, so if the consumer
+// falls behind, the ring's FullPolicy decides what happens. If the FullPolicy is
+// FullBlock, then Feed waits for room with PushWait, and stops waiting when ctx is done.
+// Feed returns nil when in is closed, or ctx.Err() if ctx is done first.
+// Feed blocks, so you will typically run it in its own goroutine.
+func (r *SyncRingT[T]) Feed(ctx context.Context, in <-chan *T) error {
+	for {
+		select {
+		case item, ok := <-in:
+			if !ok {
+				return nil
+			}
+			if r.ring.policy == FullBlock {
+				if err := r.PushWait(ctx, item); err != nil {
+					return err
+				}
+			} else {
+				r.Add(item)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// SyncRingP is a RingP that is protected by a mutex, so that it can be shared
+// between goroutines, such as a sampler and a reporter.
+type SyncRingP[T any] struct {
+	lock sync.Mutex
+	ring RingP[T]
+}
+
+// NewSyncRingP creates a new goroutine-safe ring buffer.
+// The arguments are the same as for NewRingP.
+func NewSyncRingP[T any](sizePlus1 int, opts ...Option) *SyncRingP[T] {
+	return &SyncRingP[T]{
+		ring: NewRingP[T](sizePlus1, opts...),
+	}
+}
+
+// Len returns the number of elements in the buffer
+func (r *SyncRingP[T]) Len() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.ring.Len()
+}
+
+// Next returns the next item in the ring, or the zero object if the ring is empty
+func (r *SyncRingP[T]) Next() T {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.ring.Next()
+}
+
+// Peek returns the Tail+i element from the buffer.
+func (r *SyncRingP[T]) Peek(i int) T {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.ring.Peek(i)
+}
+
+// Add an item to the buffer.
+// If the buffer is full, then the ring's FullPolicy decides what happens, as for RingP.Add.
+// Add returns the item that was erased, or the new item if it was rejected, and true.
+func (r *SyncRingP[T]) Add(item T) (evicted T, wasEvicted bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.ring.Add(item)
+}
+
+// SyncWeightedRingT is a WeightedRingT that is protected by a mutex, so that it can
+// be shared between goroutines, such as a producer and a consumer.
+type SyncWeightedRingT[T any] struct {
+	lock    sync.Mutex
+	changed notifier
+	ring    WeightedRingT[T]
+}
+
+// NewSyncWeightedRingT creates a new goroutine-safe ring buffer with the specified maximum weight
+func NewSyncWeightedRingT[T any](maxWeight int64) *SyncWeightedRingT[T] {
+	return &SyncWeightedRingT[T]{
+		ring: NewWeightedRingT[T](maxWeight),
+	}
+}
+
+// Len returns the number of elements in the buffer
+func (r *SyncWeightedRingT[T]) Len() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.ring.Len()
+}
+
+// Weight returns the total weight of all items in the ring buffer
+func (r *SyncWeightedRingT[T]) Weight() int64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.ring.Weight()
+}
+
+// Next returns the next item in the ring
+func (r *SyncWeightedRingT[T]) Next() (haveItem bool, item *T, weight int64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.ring.Next()
+}
+
+// Peek returns the Tail+i element from the buffer.
+func (r *SyncWeightedRingT[T]) Peek(i int) (haveItem bool, item *T, weight int64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.ring.Peek(i)
+}
+
+// Add an item to the buffer, as for WeightedRingT.Add.
+func (r *SyncWeightedRingT[T]) Add(weight int64, item *T) (ok bool, evicted int, evictedWeight int64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.changed.broadcast()
+	return r.ring.Add(weight, item)
+}
+
+// NextWait returns the next item in the ring and its weight, waiting for an item
+// if the buffer is empty. Returns ctx.Err() if ctx is done before an item is available.
+func (r *SyncWeightedRingT[T]) NextWait(ctx context.Context) (item *T, weight int64, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for r.ring.Len() == 0 {
+		if err := r.changed.wait(ctx, &r.lock); err != nil {
+			return nil, 0, err
+		}
+	}
+	_, item, weight = r.ring.Next()
+	return item, weight, nil
+}
+
+// notifier wakes up goroutines that are waiting for a ring to change.
+// Unlike sync.Cond, waiting on a notifier can be combined with a context.
+// A notifier must only be used while holding the lock of the ring that owns it.
+type notifier struct {
+	ch chan struct{}
+}
+
+// Release lock until the next call to broadcast, or until ctx is done.
+// lock must be held when calling this function, and it is held again when the function returns.
+func (n *notifier) wait(ctx context.Context, lock *sync.Mutex) error {
+	if n.ch == nil {
+		n.ch = make(chan struct{})
+	}
+	changed := n.ch
+	lock.Unlock()
+	defer lock.Lock()
+	select {
+	case <-changed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wake up all waiters
+func (n *notifier) broadcast() {
+	if n.ch != nil {
+		close(n.ch)
+		n.ch = nil
+	}
+}
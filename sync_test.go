@@ -0,0 +1,235 @@
+package ringbuffer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncRingT(t *testing.T) {
+	ring := NewSyncRingT[obj](1000)
+	const n = 10000
+	var wait sync.WaitGroup
+	wait.Add(1)
+	var received []*obj
+	go func() {
+		defer wait.Done()
+		for len(received) < n {
+			if item := ring.Next(); item != nil {
+				received = append(received, item)
+			}
+		}
+	}()
+	for i := 0; i < n; i++ {
+		for ring.Len() == 1000 {
+		}
+		ring.Add(&obj{id: i})
+	}
+	wait.Wait()
+	for i, item := range received {
+		require.Equal(t, i, item.id)
+	}
+	require.Nil(t, ring.Peek(0))
+}
+
+func TestSyncRingTWait(t *testing.T) {
+	ring := NewSyncRingT[obj](4)
+	const n = 10000
+	ctx := context.Background()
+	var wait sync.WaitGroup
+	wait.Add(1)
+	go func() {
+		defer wait.Done()
+		for i := 0; i < n; i++ {
+			if err := ring.PushWait(ctx, &obj{id: i}); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	for i := 0; i < n; i++ {
+		item, err := ring.PopWait(ctx)
+		require.NoError(t, err)
+		require.Equal(t, i, item.id)
+	}
+	wait.Wait()
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	item, err := ring.PopWait(timeout)
+	require.Nil(t, item)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	for i := 0; i < 4; i++ {
+		ring.Add(&obj{id: i})
+	}
+	require.ErrorIs(t, ring.PushWait(timeout, &obj{}), context.DeadlineExceeded)
+	require.Equal(t, 0, ring.Peek(0).id)
+}
+
+func TestSyncRingTChannels(t *testing.T) {
+	ring := NewSyncRingT[obj](1000)
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan *obj)
+	const n = 100
+	go func() {
+		for i := 0; i < n; i++ {
+			in <- &obj{id: i}
+		}
+		close(in)
+	}()
+	require.NoError(t, ring.Feed(ctx, in))
+
+	out := ring.Drain(ctx)
+	for i := 0; i < n; i++ {
+		item := <-out
+		require.Equal(t, i, item.id)
+	}
+	cancel()
+	for range out {
+	}
+	require.ErrorIs(t, ring.Feed(ctx, make(chan *obj)), context.Canceled)
+}
+
+func TestSyncRingTFullBlock(t *testing.T) {
+	ring := NewSyncRingT[obj](2, WithFullPolicy(FullBlock))
+	const n = 1000
+	go func() {
+		for i := 0; i < n; i++ {
+			ring.Add(&obj{id: i})
+		}
+	}()
+	for i := 0; i < n; i++ {
+		item, err := ring.PopWait(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, i, item.id)
+	}
+}
+
+func TestSyncRingP(t *testing.T) {
+	ring := NewSyncRingP[pod](16)
+	const n = 10000
+	var wait sync.WaitGroup
+	wait.Add(1)
+	go func() {
+		defer wait.Done()
+		for i := 0; i < n; i++ {
+			ring.Add(pod{id: i})
+		}
+	}()
+	// The reporter sees a window of increasing values
+	last := -1
+	for last < n-1 {
+		if ring.Len() == 0 {
+			continue
+		}
+		item := ring.Next()
+		require.Greater(t, item.id, last)
+		last = item.id
+	}
+	wait.Wait()
+	require.Equal(t, pod{}, ring.Peek(0))
+}
+
+func TestSyncWeightedRingT(t *testing.T) {
+	ring := NewSyncWeightedRingT[thing](100)
+	const n = 10000
+	var wait sync.WaitGroup
+	wait.Add(1)
+	go func() {
+		defer wait.Done()
+		for i := 0; i < n; i++ {
+			ring.Add(int64(1+i%5), &thing{id: i, weight: int64(1 + i%5)})
+		}
+	}()
+	last := -1
+	for last < n-1 {
+		require.LessOrEqual(t, ring.Weight(), int64(100))
+		ok, item, weight := ring.Next()
+		if !ok {
+			continue
+		}
+		require.Greater(t, item.id, last)
+		require.Equal(t, item.weight, weight)
+		last = item.id
+	}
+	wait.Wait()
+	ok, _, _ := ring.Peek(0)
+	require.False(t, ok)
+	require.Equal(t, 0, ring.Len())
+}
+
+func TestSyncWeightedRingTNextWait(t *testing.T) {
+	// The budget is large enough that nothing is evicted
+	ring := NewSyncWeightedRingT[thing](1000000)
+	const n = 1000
+	ctx := context.Background()
+	var wait sync.WaitGroup
+	wait.Add(1)
+	go func() {
+		defer wait.Done()
+		for i := 0; i < n; i++ {
+			ring.Add(int64(i), &thing{id: i, weight: int64(i)})
+		}
+	}()
+	for i := 0; i < n; i++ {
+		item, weight, err := ring.NextWait(ctx)
+		require.NoError(t, err)
+		require.Equal(t, i, item.id)
+		require.Equal(t, int64(i), weight)
+	}
+	wait.Wait()
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	item, weight, err := ring.NextWait(timeout)
+	require.Nil(t, item)
+	require.Equal(t, int64(0), weight)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// An item that Drain removed from the ring, but could not send before ctx was done,
+// must be put back into the ring
+func TestSyncRingTDrainCancel(t *testing.T) {
+	ring := NewSyncRingT[obj](10)
+	for i := 0; i < 3; i++ {
+		ring.Add(&obj{id: i})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	out := ring.Drain(ctx)
+	// Give the goroutine time to remove the first item, and block on sending it
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	var ids []int
+	for item := range out {
+		ids = append(ids, item.id)
+	}
+	for ring.Len() != 0 {
+		ids = append(ids, ring.Next().id)
+	}
+	require.Equal(t, []int{0, 1, 2}, ids)
+}
+
+// Feed must stop waiting for room in a FullBlock ring when ctx is done
+func TestSyncRingTFeedFullBlockCancel(t *testing.T) {
+	ring := NewSyncRingT[obj](1, WithFullPolicy(FullBlock))
+	in := make(chan *obj, 2)
+	in <- &obj{id: 0}
+	in <- &obj{id: 1}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- ring.Feed(ctx, in)
+	}()
+	select {
+	case err := <-done:
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "Feed did not return when ctx was done")
+	}
+	require.Equal(t, 1, ring.Len())
+	require.Equal(t, 0, ring.Peek(0).id)
+}
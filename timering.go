@@ -0,0 +1,148 @@
+package ringbuffer
+
+import (
+	"iter"
+	"sort"
+	"time"
+)
+
+// TimeRingT is a generic ring buffer that holds pointers to a generic type T,
+// and records the time at which each item was added. Items that are older than
+// Window are erased. Like RingT, the ring also has a maximum size, and when it is
+// full, adding an item erases the oldest item.
+// Expired items are erased by Add, Next, Len and Expire.
+// Because TimeRingT knows when each item was added, it also measures how long
+// items spend in the ring before they are consumed (see Residency).
+type TimeRingT[T any] struct {
+	Window    time.Duration    // Items older than Window are erased. If zero, items never expire.
+	Clock     func() time.Time // Returns the current time. If nil, time.Now is used. Override this for tests.
+	items     RingV[timedItem[T]]
+	residency ResidencyStats
+}
+
+// ResidencyStats measure how long items spent in a ring before they were removed by Next
+type ResidencyStats struct {
+	Count uint64        // Number of items removed by Next
+	Total time.Duration // Total time that those items spent in the ring
+	Max   time.Duration // Longest time that any of those items spent in the ring
+}
+
+// Mean returns the average time that an item spent in the ring
+func (s ResidencyStats) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+type timedItem[T any] struct {
+	item  *T
+	added time.Time
+}
+
+// NewTimeRingT creates a new ring buffer that holds at most maxSize items,
+// and erases items that are older than window.
+// The maximum size must be at least 1.
+func NewTimeRingT[T any](maxSize int, window time.Duration) TimeRingT[T] {
+	return TimeRingT[T]{
+		Window: window,
+		items:  NewRingV[timedItem[T]](maxSize),
+	}
+}
+
+// Len erases expired items, and returns the number of elements in the buffer
+func (r *TimeRingT[T]) Len() int {
+	r.Expire()
+	return r.items.Len()
+}
+
+// Next erases expired items, and returns the next item in the ring, or nil if the ring is empty
+func (r *TimeRingT[T]) Next() *T {
+	now := r.now()
+	r.expire(now)
+	if r.items.Len() == 0 {
+		return nil
+	}
+	e := r.items.Next()
+	age := now.Sub(e.added)
+	r.residency.Count++
+	r.residency.Total += age
+	r.residency.Max = max(r.residency.Max, age)
+	return e.item
+}
+
+// OldestAge returns the time that the oldest item has spent in the ring, or zero if the ring is empty.
+// OldestAge does not erase expired items.
+func (r *TimeRingT[T]) OldestAge() time.Duration {
+	if r.items.Len() == 0 {
+		return 0
+	}
+	return r.now().Sub(r.items.Peek(0).added)
+}
+
+// Residency returns statistics of how long items spent in the ring before they were
+// removed by Next. Items that were erased without being consumed are not counted.
+func (r *TimeRingT[T]) Residency() ResidencyStats {
+	return r.residency
+}
+
+// Peek returns the Tail+i element from the buffer, and the time at which it was added.
+// Peek does not erase expired items, so you may want to call Expire first.
+func (r *TimeRingT[T]) Peek(i int) (item *T, added time.Time) {
+	e := r.items.Peek(i)
+	return e.item, e.added
+}
+
+// Range returns an iterator over the items that were added at or after from, and before to,
+// from oldest to newest. The items are found with a binary search, which relies on
+// Clock never going backwards.
+// Range does not erase expired items, and the ring must not be modified during iteration.
+func (r *TimeRingT[T]) Range(from, to time.Time) iter.Seq[*T] {
+	return func(yield func(*T) bool) {
+		n := r.items.Len()
+		start := sort.Search(n, func(i int) bool {
+			return !r.items.Peek(i).added.Before(from)
+		})
+		for i := start; i < n; i++ {
+			e := r.items.Peek(i)
+			if !e.added.Before(to) {
+				return
+			}
+			if !yield(e.item) {
+				return
+			}
+		}
+	}
+}
+
+// Add erases expired items, and then adds an item to the buffer.
+// If the buffer is full, erase the oldest item.
+func (r *TimeRingT[T]) Add(item *T) {
+	now := r.now()
+	r.expire(now)
+	r.items.Add(timedItem[T]{item: item, added: now})
+}
+
+// Expire erases all items that are older than Window, and returns the number of items erased.
+func (r *TimeRingT[T]) Expire() int {
+	return r.expire(r.now())
+}
+
+func (r *TimeRingT[T]) expire(now time.Time) int {
+	if r.Window == 0 {
+		return 0
+	}
+	n := 0
+	for r.items.Len() != 0 && now.Sub(r.items.Peek(0).added) > r.Window {
+		r.items.Next()
+		n++
+	}
+	return n
+}
+
+func (r *TimeRingT[T]) now() time.Time {
+	if r.Clock != nil {
+		return r.Clock()
+	}
+	return time.Now()
+}
@@ -0,0 +1,107 @@
+package ringbuffer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// A clock for tests, which only moves when we tell it to
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestTimeRingT(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	ring := NewTimeRingT[obj](3, 10*time.Second)
+	ring.Clock = clock.Now
+
+	var all []*obj
+	for i := 0; i < 4; i++ {
+		all = append(all, &obj{id: i})
+		ring.Add(all[i])
+		clock.Advance(4 * time.Second)
+	}
+	// item 0 was erased because the ring is full
+	require.Equal(t, 3, ring.items.Len())
+	item, added := ring.Peek(0)
+	require.Equal(t, all[1], item)
+	require.Equal(t, clock.now.Add(-12*time.Second), added)
+
+	// item 1 is 12 seconds old, item 2 is 8 seconds old
+	require.Equal(t, 2, ring.Len())
+	require.Equal(t, all[2], ring.Next())
+
+	// items that are exactly Window old are kept
+	clock.Advance(6 * time.Second)
+	require.Equal(t, 1, ring.Len())
+	clock.Advance(time.Nanosecond)
+	require.Equal(t, 1, ring.Expire())
+	require.Nil(t, ring.Next())
+}
+
+func TestTimeRingTRange(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	ring := NewTimeRingT[obj](10, time.Hour)
+	ring.Clock = clock.Now
+
+	var all []*obj
+	for i := 0; i < 15; i++ {
+		all = append(all, &obj{id: i})
+		ring.Add(all[i])
+		clock.Advance(time.Second)
+	}
+	// all[5] was added at start + 5s, and it is the oldest item in the ring
+
+	collect := func(from, to time.Duration) []*obj {
+		var items []*obj
+		for item := range ring.Range(start.Add(from), start.Add(to)) {
+			items = append(items, item)
+		}
+		return items
+	}
+	require.Equal(t, all[7:10], collect(7*time.Second, 10*time.Second))
+	require.Equal(t, all[7:11], collect(6500*time.Millisecond, 10500*time.Millisecond))
+	require.Equal(t, all[5:15], collect(0, 100*time.Second))
+	require.Equal(t, all[5:6], collect(0, 6*time.Second))
+	require.Nil(t, collect(20*time.Second, 30*time.Second))
+	require.Nil(t, collect(8*time.Second, 8*time.Second))
+}
+
+func TestTimeRingTResidency(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	ring := NewTimeRingT[obj](10, 0)
+	ring.Clock = clock.Now
+
+	require.Equal(t, time.Duration(0), ring.OldestAge())
+	require.Equal(t, time.Duration(0), ring.Residency().Mean())
+	for i := 0; i < 3; i++ {
+		ring.Add(&obj{id: i})
+		clock.Advance(time.Second)
+	}
+	// with a zero Window, nothing expires
+	clock.Advance(time.Hour)
+	require.Equal(t, 3, ring.Len())
+	require.Equal(t, time.Hour+3*time.Second, ring.OldestAge())
+
+	ring.Next()
+	ring.Next()
+	clock.Advance(time.Second)
+	ring.Next()
+	require.Nil(t, ring.Next())
+	stats := ring.Residency()
+	require.Equal(t, uint64(3), stats.Count)
+	require.Equal(t, time.Hour+3*time.Second, stats.Max)
+	require.Equal(t, 3*time.Hour+7*time.Second, stats.Total)
+	require.Equal(t, (3*time.Hour+7*time.Second)/3, stats.Mean())
+}
@@ -0,0 +1,40 @@
+package ringbuffer
+
+import "iter"
+
+// RingTView is a read-only view of a RingT.
+// Hand out a view instead of the ring itself when the recipient must not modify the ring.
+// The view reflects later changes to the ring.
+type RingTView[T any] struct {
+	r *RingT[T]
+}
+
+// View returns a read-only view of the ring
+func (r *RingT[T]) View() RingTView[T] {
+	return RingTView[T]{r: r}
+}
+
+// Len returns the number of elements in the buffer
+func (v RingTView[T]) Len() int {
+	return v.r.Len()
+}
+
+// Peek returns the Tail+i element from the buffer, or nil if i is out of range
+func (v RingTView[T]) Peek(i int) *T {
+	return v.r.Peek(i)
+}
+
+// PeekLast returns the most recently added item, or nil if the ring is empty
+func (v RingTView[T]) PeekLast() *T {
+	return v.r.PeekLast()
+}
+
+// All returns an iterator over the items in the ring, from oldest to newest
+func (v RingTView[T]) All() iter.Seq[*T] {
+	return v.r.All()
+}
+
+// Backward returns an iterator over the items in the ring, from newest to oldest
+func (v RingTView[T]) Backward() iter.Seq[*T] {
+	return v.r.Backward()
+}
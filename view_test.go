@@ -0,0 +1,29 @@
+package ringbuffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRingTView(t *testing.T) {
+	ring, all := makeRingT(5, 9)
+	view := ring.View()
+	require.Equal(t, 5, view.Len())
+	require.Equal(t, all[1], view.Peek(1))
+	require.Equal(t, all[4], view.PeekLast())
+	var items []*obj
+	for item := range view.All() {
+		items = append(items, item)
+	}
+	require.Equal(t, all, items)
+	items = nil
+	for item := range view.Backward() {
+		items = append(items, item)
+	}
+	require.Equal(t, all[4], items[0])
+
+	// the view follows changes to the ring
+	ring.Next()
+	require.Equal(t, 4, view.Len())
+}
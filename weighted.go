@@ -1,5 +1,14 @@
 package ringbuffer
 
+import (
+	"encoding/binary"
+	"fmt"
+	"iter"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
 // Example
 //
 // length: 8
@@ -12,25 +21,81 @@ package ringbuffer
 
 // WeightedRingT is a generic ring buffer that holds pointers to a generic type T.
 // Each element has a "weight", and we make sure that the total weight of all
-// elements inside the ring never exceed MaxWeight. The number of items in the ring
-// is not constrained, so adding elements with zero weight will eventually
-// exhaust all memory.
+// elements inside the ring never exceed MaxWeight. Unless MaxCount is set, the
+// number of items in the ring is not constrained, so adding elements with zero
+// weight will eventually exhaust all memory.
 // When popping an item from the tail of the ring, we set it's pointer to nil,
 // to ensure that the garbage collector can reclaim the memory for that item.
 type WeightedRingT[T any] struct {
-	MaxWeight int   // we guarantee that weight <= MaxWeight
-	weight    int   // current weight
-	items     []*T  // len(items) == len(weights). len(items) is a power of 2.
-	mask      uint  // mask = len(items) - 1
-	weights   []int // weights
-	tail      uint  // read from tail
-	head      uint  // write into head
+	MaxWeight int64 // we guarantee that weight <= MaxWeight
+	MaxCount  int   // if not zero, then Add ensures that Len() <= MaxCount
+
+	// If RejectOverweight is true, then Add rejects an item that is heavier than MaxWeight,
+	// less any weight reserved by ReserveWeight. Otherwise, such an item erases all other
+	// items, and is stored alone, which means that the total weight (once the reserved
+	// weight is committed) exceeds MaxWeight until it is removed.
+	RejectOverweight bool
+
+	// If MaxAge is not zero, then items older than MaxAge are erased by Add and Expire,
+	// even if the ring is not over MaxWeight. Items that were added while MaxAge was zero
+	// are treated as if they were added by the first Add after MaxAge was set.
+	MaxAge time.Duration
+	Clock  func() time.Time // Returns the current time. If nil, time.Now is used. Override this for tests.
+
+	// Evict chooses the item that is erased when the ring is over MaxWeight or MaxCount.
+	// It returns the index of the item, where 0 is the oldest item, as for Peek.
+	// If Evict is nil, the oldest item is erased. See EvictLightest and EvictMin.
+	// Expired items (see MaxAge) are always erased oldest first.
+	Evict func(r *WeightedRingT[T]) int
+
+	// If EvictBoundary is not nil, then the ring erases whole groups of items, such as a
+	// video keyframe and the frames that depend on it. EvictBoundary returns true for
+	// an item that starts a group. After erasing the oldest items, the ring keeps
+	// erasing until the oldest remaining item starts a group. EvictBoundary only
+	// applies when Evict is nil.
+	EvictBoundary func(item *T) bool
+
+	// If CheckInvariants is true, then every change to the ring verifies that the
+	// weights are not negative, that they add up to Weight(), and that adding an
+	// item does not overflow the total weight. A violation causes a panic.
+	// The check is O(n) per change, so only enable this while debugging.
+	CheckInvariants bool
+
+	weight   int64       // current weight
+	reserved int64       // weight reserved by ReserveWeight
+	items    []*T        // len(items) == len(weights). len(items) is a power of 2.
+	mask     uint        // mask = len(items) - 1
+	weights  []int64     // weights
+	added    []time.Time // time at which each item was added. nil until MaxAge is used.
+	tail     uint        // read from tail
+	head     uint        // write into head
+	gauge    *Gauge
+	onEvict  func(item *T, weight int64)
+	stats    WeightedRingTStats
+	weigh    func(item *T) int64 // see NewWeightedRingFunc
+}
+
+// WeightedRingTStats are counters of the activity of a WeightedRingT
+type WeightedRingTStats struct {
+	Evictions     uint64 // Total number of items erased by Add, SetMaxWeight and Expire
+	EvictedWeight int64  // Total weight of the items counted by Evictions
+	PeakWeight    int64  // Largest total weight that the ring has held
 }
 
 // NewWeightedRingT creates a new ring buffer with the specified maximum weight
-func NewWeightedRingT[T any](maxWeight int) WeightedRingT[T] {
+func NewWeightedRingT[T any](maxWeight int64) WeightedRingT[T] {
+	return WeightedRingT[T]{
+		MaxWeight: maxWeight,
+	}
+}
+
+// NewWeightedRingFunc creates a new ring buffer with the specified maximum weight,
+// which computes the weight of each item with the weigh function. Use AddItem to
+// add items to the ring, so that the weight always agrees with the item.
+func NewWeightedRingFunc[T any](maxWeight int64, weigh func(item *T) int64) WeightedRingT[T] {
 	return WeightedRingT[T]{
 		MaxWeight: maxWeight,
+		weigh:     weigh,
 	}
 }
 
@@ -40,12 +105,48 @@ func (r *WeightedRingT[T]) Len() int {
 }
 
 // Weight returns the total weight of all items in the ring buffer
-func (r *WeightedRingT[T]) Weight() int {
+func (r *WeightedRingT[T]) Weight() int64 {
 	return r.weight
 }
 
+// Stats returns counters of the ring's activity since it was created
+func (r *WeightedRingT[T]) Stats() WeightedRingTStats {
+	return r.stats
+}
+
+// SetGauge attaches a Gauge to the ring, which publishes Len() and Weight() for
+// other goroutines to observe. Pass nil to detach the gauge.
+func (r *WeightedRingT[T]) SetGauge(g *Gauge) {
+	r.gauge = g
+	r.publish()
+}
+
+// SetMaxWeight changes MaxWeight, and erases items (the oldest, unless Evict is set)
+// until the total weight, plus any weight reserved by ReserveWeight, is no more than
+// the new MaxWeight. Reservations are never cancelled, so if the reserved weight alone
+// exceeds the new MaxWeight, then the ring is emptied.
+// Returns the number of items that were erased, and their total weight.
+func (r *WeightedRingT[T]) SetMaxWeight(maxWeight int64) (evicted int, evictedWeight int64) {
+	r.MaxWeight = maxWeight
+	for r.weight+r.reserved > r.MaxWeight && r.Len() != 0 {
+		_, w := r.evict()
+		evicted++
+		evictedWeight += w
+	}
+	return r.evictToBoundary(evicted, evictedWeight)
+}
+
+// OnEvict sets a function that is called for every item that the ring erases
+// without returning it to the caller. This happens when making room for a new
+// item during Add, when reducing the weight in SetMaxWeight, when items expire,
+// and when calling Clear.
+// Pass nil to remove the callback.
+func (r *WeightedRingT[T]) OnEvict(f func(item *T, weight int64)) {
+	r.onEvict = f
+}
+
 // Next returns the next item in the ring
-func (r *WeightedRingT[T]) Next() (haveItem bool, item *T, weight int) {
+func (r *WeightedRingT[T]) Next() (haveItem bool, item *T, weight int64) {
 	if r.Len() == 0 {
 		return false, nil, 0
 	}
@@ -54,13 +155,109 @@ func (r *WeightedRingT[T]) Next() (haveItem bool, item *T, weight int) {
 	r.weight -= r.weights[t]
 	haveItem, item, weight = true, r.items[t], r.weights[t]
 	r.items[t] = nil // erase item, so that the garbage collector can do it's job
+	r.publish()
 	return
 }
 
+// PopNewest removes and returns the most recently added item
+func (r *WeightedRingT[T]) PopNewest() (haveItem bool, item *T, weight int64) {
+	if r.Len() == 0 {
+		return false, nil, 0
+	}
+	r.head = (r.head - 1) & r.mask
+	r.weight -= r.weights[r.head]
+	haveItem, item, weight = true, r.items[r.head], r.weights[r.head]
+	r.items[r.head] = nil // erase item, so that the garbage collector can do it's job
+	r.publish()
+	return
+}
+
+// WeightOfFirst returns the total weight of the n oldest items.
+// If n is more than Len(), then it returns the weight of all items.
+func (r *WeightedRingT[T]) WeightOfFirst(n int) int64 {
+	n = max(0, min(n, r.Len()))
+	weight := int64(0)
+	for i := 0; i < n; i++ {
+		weight += r.weights[(r.tail+uint(i))&r.mask]
+	}
+	return weight
+}
+
+// PopUntilWeight removes the oldest items until their total weight is at least freed,
+// or the ring is empty. Returns the removed items, from oldest to newest, and their total weight.
+func (r *WeightedRingT[T]) PopUntilWeight(freed int64) (items []*T, weight int64) {
+	for weight < freed && r.Len() != 0 {
+		_, item, w := r.Next()
+		items = append(items, item)
+		weight += w
+	}
+	return
+}
+
+// DrainWeight removes the oldest items, as long as their total weight does not exceed
+// maxWeight. Returns the removed items, from oldest to newest, and their total weight.
+// If the ring is not empty, then at least one item is removed, even if it is heavier
+// than maxWeight, so that a single heavy item can't stall a consumer.
+func (r *WeightedRingT[T]) DrainWeight(maxWeight int64) (items []*T, weight int64) {
+	for r.Len() != 0 {
+		w := r.weights[r.tail]
+		if len(items) != 0 && weight+w > maxWeight {
+			break
+		}
+		_, item, _ := r.Next()
+		items = append(items, item)
+		weight += w
+	}
+	return
+}
+
+// SampleWeighted picks an item at random, with a probability that is proportional
+// to its weight. Items with zero or negative weight are never picked.
+// If there are no items with positive weight, SampleWeighted returns false.
+// This scans the items, so it is O(n).
+func (r *WeightedRingT[T]) SampleWeighted(rng *rand.Rand) (haveItem bool, item *T, weight int64) {
+	total := int64(0)
+	for _, w := range r.All() {
+		total += max(w, 0)
+	}
+	if total <= 0 {
+		return false, nil, 0
+	}
+	pick := rng.Int64N(total)
+	for item, w := range r.All() {
+		if w <= 0 {
+			continue
+		}
+		if pick < w {
+			return true, item, w
+		}
+		pick -= w
+	}
+	panic("unreachable")
+}
+
+// Clear removes all items from the ring, and resets the weight to zero.
+// The backing arrays are kept for reuse, but all of the item slots are set to nil,
+// so that the garbage collector can reclaim the items.
+func (r *WeightedRingT[T]) Clear() {
+	if r.onEvict != nil {
+		for item, weight := range r.All() {
+			r.onEvict(item, weight)
+		}
+	}
+	clear(r.items)
+	clear(r.weights)
+	clear(r.added)
+	r.weight = 0
+	r.tail = 0
+	r.head = 0
+	r.publish()
+}
+
 // Peek returns the Tail+i element from the buffer.
 // Peek(0) returns the same result as Next(), except that Peek() does not
 // change any state.
-func (r *WeightedRingT[T]) Peek(i int) (haveItem bool, item *T, weight int) {
+func (r *WeightedRingT[T]) Peek(i int) (haveItem bool, item *T, weight int64) {
 	length := (r.head - r.tail) & r.mask
 	ui := uint(i)
 	if ui >= length {
@@ -70,40 +267,435 @@ func (r *WeightedRingT[T]) Peek(i int) (haveItem bool, item *T, weight int) {
 	return true, r.items[j], r.weights[j]
 }
 
+// PeekLast returns the most recently added item
+func (r *WeightedRingT[T]) PeekLast() (haveItem bool, item *T, weight int64) {
+	if r.Len() == 0 {
+		return false, nil, 0
+	}
+	j := (r.head - 1) & r.mask
+	return true, r.items[j], r.weights[j]
+}
+
+// All returns an iterator over the items in the ring and their weights,
+// from oldest to newest. The ring must not be modified during iteration.
+func (r *WeightedRingT[T]) All() iter.Seq2[*T, int64] {
+	return func(yield func(*T, int64) bool) {
+		n := uint(r.Len())
+		for i := uint(0); i < n; i++ {
+			j := (r.tail + i) & r.mask
+			if !yield(r.items[j], r.weights[j]) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns a newly allocated slice of the items in the ring, from oldest to newest
+func (r *WeightedRingT[T]) Values() []*T {
+	values := make([]*T, 0, r.Len())
+	for item := range r.All() {
+		values = append(values, item)
+	}
+	return values
+}
+
+// Weights returns a newly allocated slice of the weights of the items in the ring,
+// from oldest to newest. Weights()[i] is the weight of Values()[i].
+func (r *WeightedRingT[T]) Weights() []int64 {
+	weights := make([]int64, 0, r.Len())
+	for _, weight := range r.All() {
+		weights = append(weights, weight)
+	}
+	return weights
+}
+
+// Backward returns an iterator over the items in the ring and their weights,
+// from newest to oldest. The ring must not be modified during iteration.
+func (r *WeightedRingT[T]) Backward() iter.Seq2[*T, int64] {
+	return func(yield func(*T, int64) bool) {
+		n := uint(r.Len())
+		for i := uint(1); i <= n; i++ {
+			j := (r.head - i) & r.mask
+			if !yield(r.items[j], r.weights[j]) {
+				return
+			}
+		}
+	}
+}
+
 // Add an item to the buffer.
-// Before adding, delete enough items so that we can store this new one.
-func (r *WeightedRingT[T]) Add(weight int, item *T) {
+// Before adding, delete expired items (see MaxAge), and enough other items
+// (see Evict) so that we can store this new one.
+// Returns true, and the number of items that were deleted, and their total weight.
+// Use OnEvict to see the deleted items themselves.
+// Reserved weight (see ReserveWeight) counts towards MaxWeight, so an item
+// that is heavier than MaxWeight less the reserved weight is overweight.
+// If RejectOverweight is true, and the item is overweight, then
+// Add returns false, and the buffer is not modified.
+func (r *WeightedRingT[T]) Add(weight int64, item *T) (ok bool, evicted int, evictedWeight int64) {
+	if r.RejectOverweight && weight+r.reserved > r.MaxWeight {
+		return false, 0, 0
+	}
+
+	evicted, evictedWeight = r.Expire()
+
+	// erase items until we're no longer overweight, or over MaxCount
+	// If this new item size exceeds MaxWeight, then we store only this item.
+	n, w := 0, int64(0)
+	for !r.fits(weight) && r.Len() != 0 {
+		_, itemWeight := r.evict()
+		n++
+		w += itemWeight
+	}
+	n, w = r.evictToBoundary(n, w)
+	evicted += n
+	evictedWeight += w
+
+	r.push(weight, item)
+	return true, evicted, evictedWeight
+}
+
+// TryAdd adds an item to the buffer, and returns true, if the item fits
+// within MaxWeight (and MaxCount) without erasing any items.
+// Otherwise, TryAdd returns false, and the buffer is not modified.
+// TryAdd does not erase expired items, so call Expire first if MaxAge is used.
+func (r *WeightedRingT[T]) TryAdd(weight int64, item *T) bool {
+	if !r.fits(weight) {
+		return false
+	}
+	r.push(weight, item)
+	return true
+}
+
+// ReserveWeight reserves weight for an item that will be added later, and returns true,
+// if the weight fits within MaxWeight (and MaxCount) without erasing any items.
+// Otherwise, ReserveWeight returns false. Use this to avoid the work of producing an
+// item that would not fit. Reserved weight counts towards MaxWeight, so Add and TryAdd
+// leave room for it, but it is not included in Weight(). An Add that is overweight
+// because of the reservation is rejected if RejectOverweight is set, and otherwise
+// stored alone, as for any overweight item (see RejectOverweight).
+// Every successful ReserveWeight must be followed by CommitWeight or CancelWeight.
+func (r *WeightedRingT[T]) ReserveWeight(weight int64) bool {
+	if !r.fits(weight) {
+		return false
+	}
+	r.reserved += weight
+	return true
+}
+
+// CommitWeight adds an item whose weight was reserved by ReserveWeight.
+// weight must be the same as the weight that was reserved.
+// The item is added without erasing any items.
+func (r *WeightedRingT[T]) CommitWeight(weight int64, item *T) {
+	r.reserved -= weight
+	r.push(weight, item)
+}
+
+// CancelWeight releases weight that was reserved by ReserveWeight,
+// when the item will not be added after all.
+func (r *WeightedRingT[T]) CancelWeight(weight int64) {
+	r.reserved -= weight
+	if r.CheckInvariants {
+		r.checkInvariants()
+	}
+}
+
+// Reserved returns the weight that is reserved by ReserveWeight,
+// and not yet committed or cancelled
+func (r *WeightedRingT[T]) Reserved() int64 {
+	return r.reserved
+}
+
+// Returns true if an item of the given weight can be added without exceeding
+// MaxWeight (including reserved weight) or MaxCount
+func (r *WeightedRingT[T]) fits(weight int64) bool {
+	return r.weight+r.reserved+weight <= r.MaxWeight && (r.MaxCount <= 0 || r.Len() < r.MaxCount)
+}
+
+// Add an item to the head of the ring, growing the ring if necessary
+func (r *WeightedRingT[T]) push(weight int64, item *T) {
+	if r.CheckInvariants {
+		if weight < 0 {
+			panic(fmt.Sprintf("WeightedRingT: adding an item with negative weight %v", weight))
+		}
+		if r.weight > math.MaxInt64-weight {
+			panic(fmt.Sprintf("WeightedRingT: adding an item with weight %v overflows the total weight %v", weight, r.weight))
+		}
+	}
 	if len(r.items) == 0 || r.Len() == len(r.items)-1 {
 		// need to grow array
 		newSize := len(r.items) * 2
 		if newSize < 4 {
 			newSize = 4
 		}
-		newItems := make([]*T, newSize, newSize)
-		newWeights := make([]int, newSize, newSize)
-		orgWeight := r.weight
 		n := r.Len()
-		for i := 0; i < n; i++ {
-			_, item, w := r.Next()
-			newItems[i] = item
-			newWeights[i] = w
+		r.items = growRing(r.items, newSize, r.tail, r.head)
+		r.weights = growRing(r.weights, newSize, r.tail, r.head)
+		if r.added != nil {
+			r.added = growRing(r.added, newSize, r.tail, r.head)
 		}
-		r.items = newItems
 		r.mask = uint(newSize) - 1
-		r.weights = newWeights
 		r.tail = 0
 		r.head = uint(n)
-		r.weight = orgWeight
 	}
 
-	// erase old items until we're no longer overweight
-	// If this new item size exceeds MaxWeight, then we store only this item.
-	for r.weight+weight > r.MaxWeight && r.Len() != 0 {
-		r.Next()
+	if r.MaxAge != 0 && r.added == nil {
+		// Backfill the existing items, so that every item has a time, and Expire
+		// can't get stuck behind an item that was added before MaxAge was set.
+		r.added = make([]time.Time, len(r.items))
+		now := r.now()
+		for i := uint(0); i < uint(r.Len()); i++ {
+			r.added[(r.tail+i)&r.mask] = now
+		}
+	}
+	if r.added != nil {
+		r.added[r.head] = r.now()
 	}
-
 	r.items[r.head] = item
 	r.weights[r.head] = weight
 	r.weight += weight
+	r.stats.PeakWeight = max(r.stats.PeakWeight, r.weight)
 	r.head = (r.head + 1) & r.mask
+	r.publish()
+}
+
+// Expire erases the items that are older than MaxAge.
+// Returns the number of items that were erased, and their total weight.
+func (r *WeightedRingT[T]) Expire() (evicted int, evictedWeight int64) {
+	if r.MaxAge == 0 || r.added == nil {
+		return 0, 0
+	}
+	now := r.now()
+	for r.Len() != 0 {
+		added := r.added[r.tail]
+		if now.Sub(added) <= r.MaxAge {
+			break
+		}
+		_, w := r.evictOldest()
+		evicted++
+		evictedWeight += w
+	}
+	return r.evictToBoundary(evicted, evictedWeight)
+}
+
+// If any items were evicted, then keep evicting the oldest items until the
+// oldest item starts a group (see EvictBoundary).
+// Returns the number of evicted items, and their weight, including those that were passed in.
+func (r *WeightedRingT[T]) evictToBoundary(evicted int, evictedWeight int64) (int, int64) {
+	if evicted == 0 || r.EvictBoundary == nil || r.Evict != nil {
+		return evicted, evictedWeight
+	}
+	for r.Len() != 0 && !r.EvictBoundary(r.items[r.tail]) {
+		_, w := r.evictOldest()
+		evicted++
+		evictedWeight += w
+	}
+	return evicted, evictedWeight
+}
+
+func (r *WeightedRingT[T]) now() time.Time {
+	if r.Clock != nil {
+		return r.Clock()
+	}
+	return time.Now()
+}
+
+// AddItem adds an item to the buffer, with the weight computed by the function
+// that was passed to NewWeightedRingFunc. The result is the same as Add.
+// AddItem panics if the ring was not created by NewWeightedRingFunc.
+func (r *WeightedRingT[T]) AddItem(item *T) (ok bool, evicted int, evictedWeight int64) {
+	if r.weigh == nil {
+		panic("AddItem requires a ring created by NewWeightedRingFunc")
+	}
+	return r.Add(r.weigh(item), item)
+}
+
+// EvictLightest is an eviction strategy for WeightedRingT.Evict, which erases
+// the item with the lowest weight. If there is a tie, the oldest of those items is erased.
+func EvictLightest[T any](r *WeightedRingT[T]) int {
+	best := 0
+	for i := 1; i < r.Len(); i++ {
+		if r.weights[(r.tail+uint(i))&r.mask] < r.weights[(r.tail+uint(best))&r.mask] {
+			best = i
+		}
+	}
+	return best
+}
+
+// EvictMin returns an eviction strategy for WeightedRingT.Evict, which erases
+// the item that is ordered first by less. For example, to erase the lowest priority item:
+//
+//	ring.Evict = EvictMin(func(a, b *Frame) bool { return a.Priority < b.Priority })
+//
+// If there is a tie, the oldest of those items is erased.
+func EvictMin[T any](less func(a, b *T) bool) func(r *WeightedRingT[T]) int {
+	return func(r *WeightedRingT[T]) int {
+		best := 0
+		for i := 1; i < r.Len(); i++ {
+			if less(r.items[(r.tail+uint(i))&r.mask], r.items[(r.tail+uint(best))&r.mask]) {
+				best = i
+			}
+		}
+		return best
+	}
+}
+
+// Erase the item chosen by our eviction strategy, and return it
+func (r *WeightedRingT[T]) evict() (item *T, weight int64) {
+	if r.Evict == nil {
+		return r.evictAt(0)
+	}
+	return r.evictAt(r.Evict(r))
+}
+
+// Erase the oldest item, and return it
+func (r *WeightedRingT[T]) evictOldest() (item *T, weight int64) {
+	return r.evictAt(0)
+}
+
+// Erase the Tail+i item, and return it
+func (r *WeightedRingT[T]) evictAt(i int) (item *T, weight int64) {
+	item, weight = r.removeAt(i)
+	r.stats.Evictions++
+	r.stats.EvictedWeight += weight
+	if r.onEvict != nil {
+		r.onEvict(item, weight)
+	}
+	return
+}
+
+// Remove the Tail+i item, and return it.
+// The older items are moved one slot towards the head, to fill the gap.
+func (r *WeightedRingT[T]) removeAt(i int) (item *T, weight int64) {
+	if uint(i) >= uint(r.Len()) {
+		panic("index out of range")
+	}
+	if i == 0 {
+		_, item, weight = r.Next()
+		return
+	}
+	j := (r.tail + uint(i)) & r.mask
+	item, weight = r.items[j], r.weights[j]
+	for ; i > 0; i-- {
+		prev := (j - 1) & r.mask
+		r.items[j] = r.items[prev]
+		r.weights[j] = r.weights[prev]
+		if r.added != nil {
+			r.added[j] = r.added[prev]
+		}
+		j = prev
+	}
+	r.items[r.tail] = nil // erase item, so that the garbage collector can do it's job
+	r.tail = (r.tail + 1) & r.mask
+	r.weight -= weight
+	r.publish()
+	return
+}
+
+// MarshalBinaryFunc encodes the items in the ring and their weights, from oldest to newest,
+// into a blob that can be restored by UnmarshalBinaryFunc. Each item is encoded by calling
+// encode. The configuration of the ring, such as MaxWeight, is not encoded.
+func (r *WeightedRingT[T]) MarshalBinaryFunc(encode func(item *T) ([]byte, error)) ([]byte, error) {
+	buf := binary.LittleEndian.AppendUint32(nil, uint32(r.Len()))
+	for item, weight := range r.All() {
+		b, err := encode(item)
+		if err != nil {
+			return nil, err
+		}
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(weight))
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(b)))
+		buf = append(buf, b...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinaryFunc replaces the contents of the ring with a blob produced by
+// MarshalBinaryFunc. Each item is decoded by calling decode. The items are restored
+// exactly, without erasing any of them, even if they exceed MaxWeight or MaxCount.
+// If MaxAge is used, then the restored items are treated as if they were added now.
+// OnEvict is not called for the items that were previously in the ring.
+// If an error is returned, then the ring is not modified.
+func (r *WeightedRingT[T]) UnmarshalBinaryFunc(data []byte, decode func(b []byte) (*T, error)) error {
+	if len(data) < 4 {
+		return fmt.Errorf("ringbuffer: binary data is too short")
+	}
+	n := int(binary.LittleEndian.Uint32(data))
+	data = data[4:]
+	items := make([]*T, 0, min(n, len(data)/12))
+	weights := make([]int64, 0, cap(items))
+	for i := 0; i < n; i++ {
+		if len(data) < 12 {
+			return fmt.Errorf("ringbuffer: binary data is too short for item %v of %v", i, n)
+		}
+		weight := int64(binary.LittleEndian.Uint64(data))
+		size := int(binary.LittleEndian.Uint32(data[8:]))
+		data = data[12:]
+		if len(data) < size {
+			return fmt.Errorf("ringbuffer: binary data is too short for item %v of %v", i, n)
+		}
+		item, err := decode(data[:size])
+		if err != nil {
+			return err
+		}
+		data = data[size:]
+		items = append(items, item)
+		weights = append(weights, weight)
+	}
+	if len(data) != 0 {
+		return fmt.Errorf("ringbuffer: %v bytes of unexpected data after %v items", len(data), n)
+	}
+
+	clear(r.items)
+	r.weight = 0
+	r.tail = 0
+	r.head = 0
+	for i, item := range items {
+		r.push(weights[i], item)
+	}
+	r.publish()
+	return nil
+}
+
+// Returns a copy of the ring buffer src with newSize elements, where the items
+// from tail to head are moved to the start of the new buffer
+func growRing[E any](src []E, newSize int, tail, head uint) []E {
+	dst := make([]E, newSize)
+	if head >= tail {
+		copy(dst, src[tail:head])
+	} else {
+		first := copy(dst, src[tail:])
+		copy(dst[first:], src[:head])
+	}
+	return dst
+}
+
+// Publish our length and weight to our gauge, if we have one.
+// This is called after every change to the ring, so it also checks our invariants.
+func (r *WeightedRingT[T]) publish() {
+	if r.CheckInvariants {
+		r.checkInvariants()
+	}
+	if r.gauge != nil {
+		r.gauge.length.Store(int64(r.Len()))
+		r.gauge.weight.Store(r.weight)
+	}
+}
+
+// Panic if our accounting of weight is inconsistent
+func (r *WeightedRingT[T]) checkInvariants() {
+	sum := int64(0)
+	i := 0
+	for _, weight := range r.All() {
+		if weight < 0 {
+			panic(fmt.Sprintf("WeightedRingT: item %v of %v has negative weight %v", i, r.Len(), weight))
+		}
+		sum += weight
+		i++
+	}
+	if sum != r.weight {
+		panic(fmt.Sprintf("WeightedRingT: item weights add up to %v, but the total weight is %v", sum, r.weight))
+	}
+	if r.reserved < 0 {
+		panic(fmt.Sprintf("WeightedRingT: reserved weight %v is negative", r.reserved))
+	}
 }
@@ -1,19 +1,24 @@
 package ringbuffer
 
 import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
 
 type thing struct {
 	id     int
-	weight int
+	weight int64
 }
 
 func TestWeightedRingT(t *testing.T) {
 	val := []*thing{}
-	valW := 0
+	valW := int64(0)
 	ring := NewWeightedRingT[thing](10)
 
 	nextID := 0
@@ -41,11 +46,11 @@ func TestWeightedRingT(t *testing.T) {
 			actualExist, actualT, actualW := ring.Peek(invalidI)
 			require.Equal(t, false, actualExist)
 			require.Nil(t, actualT)
-			require.Equal(t, 0, actualW)
+			require.Equal(t, int64(0), actualW)
 		}
 	}
 
-	add := func(weight int) {
+	add := func(weight int64) {
 		t := &thing{
 			id:     nextID,
 			weight: weight,
@@ -66,7 +71,7 @@ func TestWeightedRingT(t *testing.T) {
 		if expectEmpty {
 			require.Equal(t, false, ok)
 			require.Nil(t, actual)
-			require.Equal(t, actualW, 0)
+			require.Equal(t, int64(0), actualW)
 		} else {
 			require.Equal(t, val[0], actual)
 			require.Equal(t, val[0].weight, actualW)
@@ -88,7 +93,7 @@ func TestWeightedRingT(t *testing.T) {
 	t.Logf("add i at a time")
 	for i := 0; i < 50; i++ {
 		//t.Logf("add %v", i)
-		add(i % (ring.MaxWeight + 1))
+		add(int64(i) % (ring.MaxWeight + 1))
 		validate()
 	}
 
@@ -108,3 +113,596 @@ func TestWeightedRingT(t *testing.T) {
 	}
 	validate()
 }
+
+func TestWeightedRingTGrowWrapped(t *testing.T) {
+	ring := NewWeightedRingT[thing](100)
+	var all []*thing
+	for i := 0; i < 3; i++ {
+		all = append(all, &thing{id: i, weight: int64(i)})
+		ring.Add(int64(i), all[i])
+	}
+	ring.Next()
+	ring.Next()
+	all = all[2:]
+	// tail is now at 2, and adding 2 more items wraps around, before growing
+	for i := 3; i < 20; i++ {
+		all = append(all, &thing{id: i, weight: int64(i % 3)})
+		ring.Add(int64(i%3), all[len(all)-1])
+	}
+	require.Equal(t, len(all), ring.Len())
+	weight := int64(0)
+	for i, expect := range all {
+		ok, item, w := ring.Peek(i)
+		require.True(t, ok)
+		require.Equal(t, expect, item)
+		require.Equal(t, expect.weight, w)
+		weight += w
+	}
+	require.Equal(t, weight, ring.Weight())
+}
+
+func makeWeightedRingT(maxWeight int64, n int) (WeightedRingT[thing], []*thing) {
+	ring := NewWeightedRingT[thing](maxWeight)
+	var all []*thing
+	for i := 0; i < n; i++ {
+		all = append(all, &thing{id: i, weight: int64(1 + i%3)})
+		ring.Add(all[i].weight, all[i])
+	}
+	weight := int64(0)
+	for _, item := range all {
+		weight += item.weight
+	}
+	for weight > maxWeight {
+		weight -= all[0].weight
+		all = all[1:]
+	}
+	return ring, all
+}
+
+func TestWeightedRingTPeekLastBackward(t *testing.T) {
+	ring, _ := makeWeightedRingT(10, 0)
+	ok, item, w := ring.PeekLast()
+	require.False(t, ok)
+	require.Nil(t, item)
+	require.Equal(t, int64(0), w)
+	for range ring.Backward() {
+		require.Fail(t, "empty ring must not yield")
+	}
+
+	ring, all := makeWeightedRingT(10, 9)
+	ok, item, w = ring.PeekLast()
+	require.True(t, ok)
+	require.Equal(t, all[len(all)-1], item)
+	require.Equal(t, all[len(all)-1].weight, w)
+
+	var backward []*thing
+	for item, w := range ring.Backward() {
+		require.Equal(t, item.weight, w)
+		backward = append(backward, item)
+	}
+	require.Equal(t, len(all), len(backward))
+	for i := range backward {
+		require.Equal(t, all[len(all)-1-i], backward[i])
+	}
+}
+
+func TestWeightedRingTOnEvict(t *testing.T) {
+	ring := NewWeightedRingT[thing](5)
+	var evicted []*thing
+	ring.OnEvict(func(item *thing, weight int64) {
+		require.Equal(t, item.weight, weight)
+		evicted = append(evicted, item)
+	})
+	all := []*thing{{0, 2}, {1, 2}, {2, 3}, {3, 1}}
+	for _, item := range all {
+		ring.Add(item.weight, item)
+	}
+	require.Equal(t, all[:2], evicted)
+	// Next does not call OnEvict
+	ring.Next()
+	require.Equal(t, 2, len(evicted))
+}
+
+func TestWeightedRingTAddEvicted(t *testing.T) {
+	ring := NewWeightedRingT[thing](5)
+	_, n, w := ring.Add(2, &thing{0, 2})
+	require.Equal(t, 0, n)
+	require.Equal(t, int64(0), w)
+	ring.Add(2, &thing{1, 2})
+	_, n, w = ring.Add(4, &thing{2, 4})
+	require.Equal(t, 2, n)
+	require.Equal(t, int64(4), w)
+	// Heavier than MaxWeight, so it evicts everything
+	_, n, w = ring.Add(9, &thing{3, 9})
+	require.Equal(t, 1, n)
+	require.Equal(t, int64(4), w)
+}
+
+func TestWeightedRingTPopNewest(t *testing.T) {
+	ring, all := makeWeightedRingT(10, 9)
+	weight := ring.Weight()
+	for i := len(all) - 1; i >= 0; i-- {
+		ok, item, w := ring.PopNewest()
+		require.True(t, ok)
+		require.Equal(t, all[i], item)
+		weight -= w
+		require.Equal(t, weight, ring.Weight())
+		require.Equal(t, i, ring.Len())
+	}
+	require.Equal(t, int64(0), weight)
+	ok, item, w := ring.PopNewest()
+	require.False(t, ok)
+	require.Nil(t, item)
+	require.Equal(t, int64(0), w)
+	for _, item := range ring.items {
+		require.Nil(t, item)
+	}
+}
+
+func TestWeightedRingTSetMaxWeight(t *testing.T) {
+	ring := NewWeightedRingT[thing](10)
+	for i := 0; i < 4; i++ {
+		ring.Add(2, &thing{i, 2})
+	}
+	var evicted []int
+	ring.OnEvict(func(item *thing, weight int64) { evicted = append(evicted, item.id) })
+	n, w := ring.SetMaxWeight(5)
+	require.Equal(t, 2, n)
+	require.Equal(t, int64(4), w)
+	require.Equal(t, []int{0, 1}, evicted)
+	require.Equal(t, int64(5), ring.MaxWeight)
+	require.Equal(t, int64(4), ring.Weight())
+
+	n, _ = ring.SetMaxWeight(20)
+	require.Equal(t, 0, n)
+	n, _ = ring.SetMaxWeight(0)
+	require.Equal(t, 2, n)
+	require.Equal(t, 0, ring.Len())
+
+	// Reserved weight counts towards the new MaxWeight
+	ring = NewWeightedRingT[thing](10)
+	for i := 0; i < 5; i++ {
+		ring.Add(1, &thing{i, 1})
+	}
+	require.True(t, ring.ReserveWeight(5))
+	n, w = ring.SetMaxWeight(6)
+	require.Equal(t, 4, n)
+	require.Equal(t, int64(4), w)
+	require.Equal(t, int64(1), ring.Weight())
+	require.Equal(t, int64(5), ring.Reserved())
+	ring.CommitWeight(5, &thing{5, 5})
+	require.Equal(t, int64(6), ring.Weight())
+}
+
+func TestWeightedRingTMaxCount(t *testing.T) {
+	ring := NewWeightedRingT[thing](10)
+	ring.MaxCount = 3
+	for i := 0; i < 5; i++ {
+		ring.Add(0, &thing{i, 0})
+	}
+	require.Equal(t, 3, ring.Len())
+	_, first, _ := ring.Peek(0)
+	require.Equal(t, 2, first.id)
+
+	// Weight still applies
+	_, n, w := ring.Add(11, &thing{5, 11})
+	require.Equal(t, 3, n)
+	require.Equal(t, int64(0), w)
+	require.Equal(t, 1, ring.Len())
+}
+
+func TestNewWeightedRingFunc(t *testing.T) {
+	ring := NewWeightedRingFunc(5, func(item *thing) int64 { return item.weight })
+	ring.AddItem(&thing{0, 2})
+	ring.AddItem(&thing{1, 2})
+	_, n, w := ring.AddItem(&thing{2, 3})
+	require.Equal(t, 1, n)
+	require.Equal(t, int64(2), w)
+	require.Equal(t, int64(5), ring.Weight())
+	_, item, w := ring.PeekLast()
+	require.Equal(t, 2, item.id)
+	require.Equal(t, int64(3), w)
+
+	plain := NewWeightedRingT[thing](5)
+	require.Panics(t, func() { plain.AddItem(&thing{0, 1}) })
+}
+
+func TestWeightedRingTRejectOverweight(t *testing.T) {
+	ring := NewWeightedRingT[thing](5)
+	ring.RejectOverweight = true
+	ok, _, _ := ring.Add(5, &thing{0, 5})
+	require.True(t, ok)
+	ok, n, w := ring.Add(6, &thing{1, 6})
+	require.False(t, ok)
+	require.Equal(t, 0, n)
+	require.Equal(t, int64(0), w)
+	require.Equal(t, 1, ring.Len())
+	require.Equal(t, int64(5), ring.Weight())
+
+	ring.RejectOverweight = false
+	ok, n, w = ring.Add(6, &thing{1, 6})
+	require.True(t, ok)
+	require.Equal(t, 1, n)
+	require.Equal(t, int64(5), w)
+	require.Equal(t, int64(6), ring.Weight())
+}
+
+func TestWeightedRingTTryAdd(t *testing.T) {
+	ring := NewWeightedRingT[thing](5)
+	var evicted int
+	ring.OnEvict(func(item *thing, weight int64) { evicted++ })
+	require.True(t, ring.TryAdd(3, &thing{0, 3}))
+	require.False(t, ring.TryAdd(3, &thing{1, 3}))
+	require.True(t, ring.TryAdd(2, &thing{1, 2}))
+	require.False(t, ring.TryAdd(1, &thing{2, 1}))
+	require.True(t, ring.TryAdd(0, &thing{2, 0}))
+	require.Equal(t, 3, ring.Len())
+	require.Equal(t, int64(5), ring.Weight())
+	require.Equal(t, 0, evicted)
+
+	ring.MaxCount = 3
+	require.False(t, ring.TryAdd(0, &thing{3, 0}))
+}
+
+func TestWeightedRingTAll(t *testing.T) {
+	ring, all := makeWeightedRingT(10, 9)
+	var forward []*thing
+	for item, w := range ring.All() {
+		require.Equal(t, item.weight, w)
+		forward = append(forward, item)
+	}
+	require.Equal(t, all, forward)
+
+	// Stop early
+	n := 0
+	for range ring.All() {
+		n++
+		break
+	}
+	require.Equal(t, 1, n)
+}
+
+func TestWeightedRingTValuesWeights(t *testing.T) {
+	ring, all := makeWeightedRingT(10, 9)
+	require.Equal(t, all, ring.Values())
+	weights := ring.Weights()
+	require.Equal(t, len(all), len(weights))
+	for i := range all {
+		require.Equal(t, all[i].weight, weights[i])
+	}
+
+	empty := NewWeightedRingT[thing](10)
+	require.Empty(t, empty.Values())
+	require.Empty(t, empty.Weights())
+}
+
+func TestWeightedRingTClear(t *testing.T) {
+	ring, all := makeWeightedRingT(10, 9)
+	var evicted []*thing
+	ring.OnEvict(func(item *thing, weight int64) { evicted = append(evicted, item) })
+	var gauge Gauge
+	ring.SetGauge(&gauge)
+	capacity := len(ring.items)
+	ring.Clear()
+	require.Equal(t, all, evicted)
+	require.Equal(t, 0, ring.Len())
+	require.Equal(t, int64(0), ring.Weight())
+	require.Equal(t, int64(0), gauge.Weight())
+	require.Equal(t, capacity, len(ring.items))
+	for _, item := range ring.items {
+		require.Nil(t, item)
+	}
+	ring.Add(1, all[0])
+	require.Equal(t, []*thing{all[0]}, ring.Values())
+}
+
+func TestWeightedRingTMaxAge(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	ring := NewWeightedRingT[thing](100)
+	ring.MaxAge = 10 * time.Second
+	ring.Clock = clock.Now
+	var evicted []int
+	ring.OnEvict(func(item *thing, weight int64) { evicted = append(evicted, item.id) })
+
+	// Add enough items to grow the ring, so that the times are moved along with the items
+	for i := 0; i < 10; i++ {
+		ring.Add(1, &thing{i, 1})
+		clock.Advance(time.Second)
+	}
+	// The first item is now 10 seconds old, which is not more than MaxAge
+	n, w := ring.Expire()
+	require.Equal(t, 0, n)
+	require.Equal(t, int64(0), w)
+
+	clock.Advance(2500 * time.Millisecond)
+	n, w = ring.Expire()
+	require.Equal(t, 3, n)
+	require.Equal(t, int64(3), w)
+	require.Equal(t, []int{0, 1, 2}, evicted)
+
+	// Add expires items too, and counts them as evicted
+	clock.Advance(time.Second)
+	ok, n, w := ring.Add(1, &thing{10, 1})
+	require.True(t, ok)
+	require.Equal(t, 1, n)
+	require.Equal(t, int64(1), w)
+	require.Equal(t, 7, ring.Len())
+
+	ring.Clear()
+	ring.Add(1, &thing{11, 1})
+	clock.Advance(time.Minute)
+	n, _ = ring.Expire()
+	require.Equal(t, 1, n)
+}
+
+func TestWeightedRingTMaxAgeSetLate(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	ring := NewWeightedRingT[thing](100)
+	ring.Clock = clock.Now
+	ring.Add(1, &thing{0, 1})
+	ring.Add(1, &thing{1, 1})
+
+	// The items that were added before MaxAge was set get the time of the next Add,
+	// so they expire along with it, and don't block newer items from expiring.
+	ring.MaxAge = 10 * time.Second
+	ring.Add(1, &thing{2, 1})
+	clock.Advance(5 * time.Second)
+	ring.Add(1, &thing{3, 1})
+	clock.Advance(6 * time.Second)
+	n, w := ring.Expire()
+	require.Equal(t, 3, n)
+	require.Equal(t, int64(3), w)
+	_, item, _ := ring.Peek(0)
+	require.Equal(t, 3, item.id)
+}
+
+func TestWeightedRingTPopUntilWeight(t *testing.T) {
+	// weights are 1, 2, 3, 1, 2, 3
+	ring, all := makeWeightedRingT(100, 6)
+	require.Equal(t, int64(0), ring.WeightOfFirst(0))
+	require.Equal(t, int64(6), ring.WeightOfFirst(3))
+	require.Equal(t, int64(12), ring.WeightOfFirst(100))
+	require.Equal(t, int64(0), ring.WeightOfFirst(-1))
+
+	items, w := ring.PopUntilWeight(4)
+	require.Equal(t, all[:3], items)
+	require.Equal(t, int64(6), w)
+	require.Equal(t, int64(6), ring.Weight())
+
+	items, w = ring.PopUntilWeight(0)
+	require.Empty(t, items)
+	require.Equal(t, int64(0), w)
+
+	items, w = ring.PopUntilWeight(100)
+	require.Equal(t, all[3:], items)
+	require.Equal(t, int64(6), w)
+	require.Equal(t, 0, ring.Len())
+}
+
+func TestWeightedRingTEvict(t *testing.T) {
+	ring := NewWeightedRingT[thing](10)
+	ring.Evict = EvictLightest[thing]
+	var evicted []int
+	ring.OnEvict(func(item *thing, weight int64) { evicted = append(evicted, item.id) })
+	// Move the tail along, so that the items wrap around the end of the array
+	ring.Add(0, &thing{-1, 0})
+	ring.Add(0, &thing{-2, 0})
+	ring.Next()
+	ring.Next()
+	all := []*thing{{0, 3}, {1, 1}, {2, 4}, {3, 1}}
+	for _, item := range all {
+		ring.Add(item.weight, item)
+	}
+	// Adding weight 3 must erase the two lightest items, oldest first
+	_, n, w := ring.Add(3, &thing{4, 3})
+	require.Equal(t, 2, n)
+	require.Equal(t, int64(2), w)
+	require.Equal(t, []int{1, 3}, evicted)
+	require.Equal(t, int64(10), ring.Weight())
+	require.Equal(t, []*thing{all[0], all[2], {4, 3}}, ring.Values())
+	require.Equal(t, []int64{3, 4, 3}, ring.Weights())
+
+	ring.Evict = EvictMin(func(a, b *thing) bool { return a.id > b.id })
+	evicted = nil
+	ring.SetMaxWeight(7)
+	require.Equal(t, []int{4}, evicted)
+	require.Equal(t, []*thing{all[0], all[2]}, ring.Values())
+}
+
+func TestWeightedRingTStats(t *testing.T) {
+	ring := NewWeightedRingT[thing](10)
+	ring.Add(4, &thing{0, 4})
+	ring.Add(5, &thing{1, 5})
+	ring.Add(3, &thing{2, 3})
+	require.Equal(t, WeightedRingTStats{Evictions: 1, EvictedWeight: 4, PeakWeight: 9}, ring.Stats())
+	ring.SetMaxWeight(4)
+	require.Equal(t, WeightedRingTStats{Evictions: 2, EvictedWeight: 9, PeakWeight: 9}, ring.Stats())
+	// Next and Clear are not evictions
+	ring.Add(1, &thing{3, 1})
+	ring.Next()
+	ring.Clear()
+	require.Equal(t, WeightedRingTStats{Evictions: 2, EvictedWeight: 9, PeakWeight: 9}, ring.Stats())
+}
+
+func TestWeightedRingTEvictBoundary(t *testing.T) {
+	// Every third item starts a group
+	ring := NewWeightedRingT[thing](10)
+	ring.EvictBoundary = func(item *thing) bool { return item.id%3 == 0 }
+	for i := 0; i < 10; i++ {
+		ring.Add(1, &thing{i, 1})
+	}
+	// Adding one more item erases item 0, and then 1 and 2, which depend on it
+	_, n, w := ring.Add(1, &thing{10, 1})
+	require.Equal(t, 3, n)
+	require.Equal(t, int64(3), w)
+	_, first, _ := ring.Peek(0)
+	require.Equal(t, 3, first.id)
+	require.Equal(t, 8, ring.Len())
+
+	// Until we need room again, no items are erased
+	ring.Add(1, &thing{11, 1})
+	ring.Add(1, &thing{12, 1})
+	require.Equal(t, 10, ring.Len())
+
+	n, _ = ring.SetMaxWeight(8)
+	require.Equal(t, 3, n)
+	_, first, _ = ring.Peek(0)
+	require.Equal(t, 6, first.id)
+}
+
+func TestWeightedRingTReserveWeight(t *testing.T) {
+	ring := NewWeightedRingT[thing](10)
+	ring.Add(4, &thing{0, 4})
+	require.True(t, ring.ReserveWeight(5))
+	require.False(t, ring.ReserveWeight(2))
+	require.Equal(t, int64(5), ring.Reserved())
+	require.Equal(t, int64(4), ring.Weight())
+
+	// Add must leave room for the reservation
+	_, n, _ := ring.Add(2, &thing{1, 2})
+	require.Equal(t, 1, n)
+	require.False(t, ring.TryAdd(4, &thing{2, 4}))
+
+	ring.CommitWeight(5, &thing{2, 5})
+	require.Equal(t, int64(0), ring.Reserved())
+	require.Equal(t, int64(7), ring.Weight())
+	require.Equal(t, 2, ring.Len())
+
+	require.True(t, ring.ReserveWeight(3))
+	ring.CancelWeight(3)
+	require.Equal(t, int64(0), ring.Reserved())
+	require.True(t, ring.TryAdd(3, &thing{3, 3}))
+}
+
+func TestWeightedRingTReserveWeightOverweight(t *testing.T) {
+	// Add and a reservation compete for room. With RejectOverweight, an item that
+	// only fits if the reservation is ignored is rejected.
+	ring := NewWeightedRingT[thing](10)
+	ring.RejectOverweight = true
+	ring.Add(3, &thing{0, 3})
+	require.True(t, ring.ReserveWeight(5))
+	ok, n, _ := ring.Add(6, &thing{1, 6})
+	require.False(t, ok)
+	require.Equal(t, 0, n)
+	require.Equal(t, 1, ring.Len())
+	ok, n, _ = ring.Add(5, &thing{2, 5})
+	require.True(t, ok)
+	require.Equal(t, 1, n)
+	ring.CommitWeight(5, &thing{3, 5})
+	require.Equal(t, int64(10), ring.Weight())
+
+	// Without RejectOverweight, the item is stored alone, as for any overweight item
+	ring = NewWeightedRingT[thing](10)
+	ring.Add(3, &thing{0, 3})
+	require.True(t, ring.ReserveWeight(5))
+	ok, n, _ = ring.Add(6, &thing{1, 6})
+	require.True(t, ok)
+	require.Equal(t, 1, n)
+	require.Equal(t, 1, ring.Len())
+}
+
+func TestWeightedRingTCheckInvariants(t *testing.T) {
+	ring := NewWeightedRingT[thing](math.MaxInt64)
+	ring.CheckInvariants = true
+	ring.Add(5, &thing{0, 5})
+	require.Panics(t, func() { ring.Add(-1, &thing{1, -1}) })
+	require.Panics(t, func() { ring.Add(math.MaxInt64, &thing{1, math.MaxInt64}) })
+	require.Panics(t, func() { ring.CancelWeight(1) })
+	ring.reserved = 0
+
+	// Corrupt the accounting, which must be detected by the next change
+	ring.Add(2, &thing{1, 2})
+	ring.weight++
+	require.Panics(t, func() { ring.Next() })
+
+	// Without CheckInvariants, negative weights are accepted
+	plain := NewWeightedRingT[thing](10)
+	plain.Add(-1, &thing{0, -1})
+	require.Equal(t, int64(-1), plain.Weight())
+}
+
+func TestWeightedRingTMarshalBinaryFunc(t *testing.T) {
+	encode := func(item *thing) ([]byte, error) {
+		return []byte(strconv.Itoa(item.id)), nil
+	}
+	decode := func(b []byte) (*thing, error) {
+		id, err := strconv.Atoi(string(b))
+		return &thing{id: id}, err
+	}
+	ring, all := makeWeightedRingT(10, 9)
+	data, err := ring.MarshalBinaryFunc(encode)
+	require.NoError(t, err)
+
+	restored := NewWeightedRingT[thing](10)
+	restored.Add(1, &thing{100, 1})
+	require.NoError(t, restored.UnmarshalBinaryFunc(data, decode))
+	require.Equal(t, ring.Weights(), restored.Weights())
+	require.Equal(t, ring.Weight(), restored.Weight())
+	i := 0
+	for item := range restored.All() {
+		require.Equal(t, all[i].id, item.id)
+		i++
+	}
+	require.Equal(t, len(all), i)
+
+	// Errors leave the ring unchanged
+	require.Error(t, restored.UnmarshalBinaryFunc(data[:len(data)-1], decode))
+	require.Error(t, restored.UnmarshalBinaryFunc(append(data, 0), decode))
+	require.Error(t, restored.UnmarshalBinaryFunc(data, func(b []byte) (*thing, error) {
+		return nil, errors.New("bad item")
+	}))
+	require.Equal(t, len(all), restored.Len())
+
+	empty := NewWeightedRingT[thing](10)
+	data, err = empty.MarshalBinaryFunc(encode)
+	require.NoError(t, err)
+	require.NoError(t, restored.UnmarshalBinaryFunc(data, decode))
+	require.Equal(t, 0, restored.Len())
+	require.Equal(t, int64(0), restored.Weight())
+}
+
+func TestWeightedRingTSampleWeighted(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	ring := NewWeightedRingT[thing](100)
+	ok, _, _ := ring.SampleWeighted(rng)
+	require.False(t, ok)
+	ring.Add(0, &thing{0, 0})
+	ok, _, _ = ring.SampleWeighted(rng)
+	require.False(t, ok)
+
+	ring.Add(1, &thing{1, 1})
+	ring.Add(3, &thing{2, 3})
+	counts := make([]int, 3)
+	const n = 10000
+	for i := 0; i < n; i++ {
+		ok, item, w := ring.SampleWeighted(rng)
+		require.True(t, ok)
+		require.Equal(t, item.weight, w)
+		counts[item.id]++
+	}
+	require.Equal(t, 0, counts[0])
+	require.InDelta(t, n/4, counts[1], n/20)
+	require.InDelta(t, 3*n/4, counts[2], n/20)
+}
+
+func TestWeightedRingTDrainWeight(t *testing.T) {
+	// weights are 1, 2, 3, 1, 2, 3
+	ring, all := makeWeightedRingT(100, 6)
+	items, w := ring.DrainWeight(5)
+	require.Equal(t, all[:2], items)
+	require.Equal(t, int64(3), w)
+
+	// The next item is heavier than the batch, so it is returned alone
+	items, w = ring.DrainWeight(2)
+	require.Equal(t, all[2:3], items)
+	require.Equal(t, int64(3), w)
+
+	items, w = ring.DrainWeight(100)
+	require.Equal(t, all[3:], items)
+	require.Equal(t, int64(6), w)
+	require.Equal(t, int64(0), ring.Weight())
+
+	items, w = ring.DrainWeight(100)
+	require.Empty(t, items)
+	require.Equal(t, int64(0), w)
+}
@@ -0,0 +1,116 @@
+package ringbuffer
+
+import "iter"
+
+// WeightedRingV is a generic ring buffer that holds values of a generic type T.
+// It has the same semantics as WeightedRingT: each element has a "weight", and we
+// make sure that the total weight of all elements inside the ring never exceeds
+// MaxWeight. But because WeightedRingV stores values instead of pointers,
+// adding an item does not require a separate allocation per item.
+// When popping an item from the tail of the ring, we set it's slot to the zero value,
+// to ensure that the garbage collector can reclaim anything that the item references.
+type WeightedRingV[T any] struct {
+	MaxWeight int64   // we guarantee that weight <= MaxWeight
+	weight    int64   // current weight
+	items     []T     // len(items) == len(weights). len(items) is a power of 2.
+	mask      uint    // mask = len(items) - 1
+	weights   []int64 // weights
+	tail      uint    // read from tail
+	head      uint    // write into head
+}
+
+// NewWeightedRingV creates a new ring buffer with the specified maximum weight
+func NewWeightedRingV[T any](maxWeight int64) WeightedRingV[T] {
+	return WeightedRingV[T]{
+		MaxWeight: maxWeight,
+	}
+}
+
+// Len returns the number of elements in the buffer
+func (r *WeightedRingV[T]) Len() int {
+	return int((r.head - r.tail) & r.mask)
+}
+
+// Weight returns the total weight of all items in the ring buffer
+func (r *WeightedRingV[T]) Weight() int64 {
+	return r.weight
+}
+
+// Next returns the next item in the ring
+func (r *WeightedRingV[T]) Next() (haveItem bool, item T, weight int64) {
+	if r.Len() == 0 {
+		return
+	}
+	t := r.tail
+	r.tail = (r.tail + 1) & r.mask
+	r.weight -= r.weights[t]
+	haveItem, item, weight = true, r.items[t], r.weights[t]
+	var zero T
+	r.items[t] = zero // erase item, so that the garbage collector can do it's job
+	return
+}
+
+// Peek returns the Tail+i element from the buffer.
+// Peek(0) returns the same result as Next(), except that Peek() does not
+// change any state.
+func (r *WeightedRingV[T]) Peek(i int) (haveItem bool, item T, weight int64) {
+	length := (r.head - r.tail) & r.mask
+	ui := uint(i)
+	if ui >= length {
+		return
+	}
+	j := (r.tail + ui) & r.mask
+	return true, r.items[j], r.weights[j]
+}
+
+// PeekLast returns the most recently added item
+func (r *WeightedRingV[T]) PeekLast() (haveItem bool, item T, weight int64) {
+	if r.Len() == 0 {
+		return
+	}
+	j := (r.head - 1) & r.mask
+	return true, r.items[j], r.weights[j]
+}
+
+// All returns an iterator over the items in the ring and their weights,
+// from oldest to newest. The ring must not be modified during iteration.
+func (r *WeightedRingV[T]) All() iter.Seq2[T, int64] {
+	return func(yield func(T, int64) bool) {
+		n := uint(r.Len())
+		for i := uint(0); i < n; i++ {
+			j := (r.tail + i) & r.mask
+			if !yield(r.items[j], r.weights[j]) {
+				return
+			}
+		}
+	}
+}
+
+// Add an item to the buffer.
+// Before adding, delete enough items so that we can store this new one.
+// If this new item exceeds MaxWeight, then we store only this item.
+// Returns the number of items that were deleted, and their total weight.
+func (r *WeightedRingV[T]) Add(weight int64, item T) (evicted int, evictedWeight int64) {
+	for r.weight+weight > r.MaxWeight && r.Len() != 0 {
+		_, _, w := r.Next()
+		evicted++
+		evictedWeight += w
+	}
+
+	if len(r.items) == 0 || r.Len() == len(r.items)-1 {
+		// need to grow array
+		newSize := max(len(r.items)*2, 4)
+		n := r.Len()
+		r.items = growRing(r.items, newSize, r.tail, r.head)
+		r.weights = growRing(r.weights, newSize, r.tail, r.head)
+		r.mask = uint(newSize) - 1
+		r.tail = 0
+		r.head = uint(n)
+	}
+
+	r.items[r.head] = item
+	r.weights[r.head] = weight
+	r.weight += weight
+	r.head = (r.head + 1) & r.mask
+	return
+}
@@ -0,0 +1,59 @@
+package ringbuffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeightedRingV(t *testing.T) {
+	ring := NewWeightedRingV[pod](10)
+	ok, item, w := ring.Next()
+	require.False(t, ok)
+	require.Equal(t, pod{}, item)
+	require.Equal(t, int64(0), w)
+
+	// Move the tail along, so that growing the ring must unwrap the items
+	ring.Add(0, pod{-1})
+	ring.Add(0, pod{-2})
+	ring.Next()
+	ring.Next()
+
+	var all []pod
+	weight := int64(0)
+	for i := 0; i < 20; i++ {
+		all = append(all, pod{i})
+		ring.Add(int64(i%3), pod{i})
+		weight += int64(i % 3)
+		for weight > ring.MaxWeight {
+			weight -= int64(all[0].id % 3)
+			all = all[1:]
+		}
+	}
+	require.Equal(t, len(all), ring.Len())
+	require.Equal(t, weight, ring.Weight())
+	i := 0
+	for item, w := range ring.All() {
+		require.Equal(t, all[i], item)
+		require.Equal(t, int64(all[i].id%3), w)
+		i++
+	}
+	ok, item, _ = ring.PeekLast()
+	require.True(t, ok)
+	require.Equal(t, all[len(all)-1], item)
+	ok, item, _ = ring.Peek(0)
+	require.True(t, ok)
+	require.Equal(t, all[0], item)
+
+	n, w := ring.Add(20, pod{100})
+	require.Equal(t, len(all), n)
+	require.Equal(t, weight, w)
+	ok, item, w = ring.Next()
+	require.True(t, ok)
+	require.Equal(t, pod{100}, item)
+	require.Equal(t, int64(20), w)
+	require.Equal(t, 0, ring.Len())
+	for _, item := range ring.items {
+		require.Equal(t, pod{}, item)
+	}
+}